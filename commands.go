@@ -2,19 +2,30 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/rosti-cz/cli/src/config"
+	"github.com/rosti-cz/cli/src/crontab"
+	"github.com/rosti-cz/cli/src/healthcheck"
+	"github.com/rosti-cz/cli/src/history"
+	"github.com/rosti-cz/cli/src/lock"
 	"github.com/rosti-cz/cli/src/parser"
+	"github.com/rosti-cz/cli/src/progress"
 	"github.com/rosti-cz/cli/src/rostiapi"
 	"github.com/rosti-cz/cli/src/scanner"
+	"github.com/rosti-cz/cli/src/secrets"
+	"github.com/rosti-cz/cli/src/shell"
 	"github.com/rosti-cz/cli/src/ssh"
 	"github.com/rosti-cz/cli/src/state"
+	"github.com/rosti-cz/cli/src/supervisor"
 	"github.com/urfave/cli/v2"
 	"golang.org/x/crypto/ssh/terminal"
 	"gopkg.in/yaml.v2"
@@ -58,17 +69,54 @@ func commandImport(c *cli.Context) error {
 	return nil
 }
 
+// Supervisor config of the default application created with a new container
+const defaultSupervisorConfigPath = "/srv/conf/supervisor.d/app.conf"
+
 // Deploys or re-deploys an application
-func commandUp(c *cli.Context) error {
+func commandUp(c *cli.Context) (err error) {
+	if c.Bool("all") || c.Args().Len() > 0 {
+		return commandUpWorkspace(c)
+	}
+
+	if c.Bool("dry-run") {
+		return commandPlan(c)
+	}
+
+	rateLimit, err := progress.ParseRate(c.String("limit-rate"))
+	if err != nil {
+		return err
+	}
+
 	config := config.Load()
 
+	record := history.NewRecord()
+	var sshClient *ssh.Client
+	defer func() {
+		saveDeployRecord(record, sshClient, err)
+	}()
+
 	// Rostifile and statefile
 	cYellow.Println(".. loading Rostifile")
-	rostifile, err := parser.Parse()
+	rostifile, report, err := parser.Check()
+	if err != nil {
+		return err
+	}
+	printReport(report)
+	if !report.OK() {
+		return fmt.Errorf("Rostifile is not valid, %d error(s) found", len(report.Errors))
+	}
+
+	err = decryptSecrets(rostifile)
 	if err != nil {
 		return err
 	}
 
+	record.RostifileHash, err = parser.Checksum()
+	if err != nil {
+		return err
+	}
+	record.Commit, record.Dirty = gitInfo(rostifile.SourcePath)
+
 	client := rostiapi.Client{
 		Token:      config.Token,
 		ExtraError: os.Stderr,
@@ -80,25 +128,21 @@ func commandUp(c *cli.Context) error {
 		return err
 	}
 	defer state.Write(appState)
+	record.AppID = appState.ApplicationID
+
+	// Build runs before anything is changed so a failed build doesn't affect the application
+	if len(rostifile.BuildCommands) > 0 {
+		cYellow.Println(".. running build commands")
+	}
+	err = runBuildCommands(rostifile)
+	if err != nil {
+		return err
+	}
 
 	// SSH key
-	if len(appState.SSHKeyPath) == 0 {
-		cYellow.Println(".. SSH key not found in the state file, trying to figure this out")
-		privateSSHKeyPath, _, err := findSSHKey()
-		if err != nil {
-			return fmt.Errorf("SSH key problem: %w", err)
-		}
-		appState.SSHKeyPath = privateSSHKeyPath
-	} else {
-		_, err := os.Stat(appState.SSHKeyPath)
-		if os.IsNotExist(err) {
-			cYellow.Println(".. SSH key configured in state file but the file doesn't not exist, trying to figure this out")
-			privateSSHKeyPath, _, err := findSSHKey()
-			if err != nil {
-				return fmt.Errorf("SSH key problem: %w", err)
-			}
-			appState.SSHKeyPath = privateSSHKeyPath
-		}
+	err = ensureSSHKey(appState)
+	if err != nil {
+		return err
 	}
 
 	// Pick the right company
@@ -125,13 +169,11 @@ func commandUp(c *cli.Context) error {
 		return err
 	}
 
+	record.Plan = rostifile.Plan
+	record.Runtime = selectedRuntime
+
 	// Figure out mode
-	var mode string
-	if rostifile.HTTPS {
-		mode = "https+le"
-	} else {
-		mode = "http"
-	}
+	mode := appMode(rostifile)
 
 	// Create or update the application
 	var newApp *rostiapi.App
@@ -153,6 +195,36 @@ func commandUp(c *cli.Context) error {
 			}
 		}
 
+		// Technology is checked before the application is changed, runtime
+		// that is going to change is checked after the update.
+		if app.Image == selectedRuntime {
+			cYellow.Println(".. loading application status")
+			status, err := client.GetAppStatus(appState.ApplicationID)
+			if err != nil {
+				return fmt.Errorf("GetAppStatus error: %v", err)
+			}
+
+			err = validateTechnology(rostifile, status.Techs, app.Image)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Only one deploy can run at a time so the lock is taken before anything is changed
+		sshClient, err = sshClientForApp(&app, appState)
+		if err != nil {
+			return err
+		}
+		err = waitForSSH(sshClient)
+		if err != nil {
+			return err
+		}
+		releaseLock, err := acquireDeployLock(sshClient)
+		if err != nil {
+			return err
+		}
+		defer releaseLock()
+
 		sshPubKey, err := readLocalSSHPubKey(appState.SSHPublicKeyPath())
 		if err != nil {
 			return err
@@ -161,6 +233,11 @@ func commandUp(c *cli.Context) error {
 		// Use update
 		cYellow.Printf(".. updating existing application %s_%d \n", rostifile.Name, appState.ApplicationID)
 
+		// Application without plan in Rostifile keeps its current plan
+		if planID == 0 {
+			planID = app.Plan
+		}
+
 		app = rostiapi.App{
 			ID:      appState.ApplicationID,
 			Name:    rostifile.Name,
@@ -172,12 +249,16 @@ func commandUp(c *cli.Context) error {
 			AppPort: rostifile.AppPort,
 		}
 
-		// TODO: save assigned domains into Rostifile
-
 		newApp, err = client.UpdateApp(&app)
 		if err != nil {
 			return err
 		}
+
+		// Changed runtime restarts the container
+		err = waitForSSH(sshClient)
+		if err != nil {
+			return err
+		}
 	} else {
 		// Create a new app
 		cYellow.Printf(".. creating a new application %s \n", rostifile.Name)
@@ -202,69 +283,29 @@ func commandUp(c *cli.Context) error {
 			return err
 		}
 		appState.ApplicationID = newApp.ID
+		record.AppID = newApp.ID
 
 		appCreated = true
-	}
-
-	// SSH client initialization
-	if len(newApp.SSHAccess) == 0 {
-		return errors.New("no SSH access found")
-	}
 
-	sshClient := ssh.Client{
-		Server:     newApp.SSHAccess[0].Hostname,
-		Port:       int(newApp.SSHAccess[0].Port),
-		Username:   newApp.SSHAccess[0].Username,
-		SSHKeyPath: appState.SSHKeyPath,
-	}
-
-	if sshClient.IsKeyPasswordProtected() {
-		counter := 0
-		for {
-			fmt.Print("SSH key password: ")
-			sshClient.Passphrase, err = terminal.ReadPassword(0)
-			fmt.Println()
-			if err != nil {
-				return fmt.Errorf("ssh key password input error: %v", err)
-			}
-
-			ok, err := sshClient.IsPasswordOk()
-			if err != nil {
-				return fmt.Errorf("ssh key password check error: %v", err)
-			}
-
-			if ok {
-				break
-			}
-
-			counter += 1
-
-			if counter >= 3 {
-				return fmt.Errorf("too many attempts for the SSH key password")
-			}
+		// New application can be locked only after it's created
+		sshClient, err = sshClientForApp(newApp, appState)
+		if err != nil {
+			return err
 		}
-	}
-
-	// Test SSH connection
-	cYellow.Println(".. waiting for SSH daemon to get ready")
-	testCounter := 0
-	for {
-		_, err := sshClient.Run("echo 1")
-		if err == nil {
-			cGreen.Println("     ready")
-			break
+		err = waitForSSH(sshClient)
+		if err != nil {
+			return err
 		}
-
-		if testCounter > 12 {
-			// This prints the last error that occurs. It turned out the problem can actually be
-			// something else because this is the first time we try to connect to the SSH server.
-			cRed.Println(err.Error())
-			return errors.New("SSH daemon has not started in time")
+		releaseLock, err := acquireDeployLock(sshClient)
+		if err != nil {
+			return err
 		}
+		defer releaseLock()
+	}
 
-		testCounter++
-
-		time.Sleep(5 * time.Second)
+	// Domain assigned by the API is saved so the application keeps it
+	if len(rostifile.Domains) == 0 {
+		saveAssignedDomains(newApp.Domains)
 	}
 
 	// Setup technology
@@ -272,8 +313,13 @@ func commandUp(c *cli.Context) error {
 		// Call rosti.sh to setup environment for selected technology
 		if len(rostifile.Technology) > 0 {
 			cRed.Println(".. deleting default code")
+			defaultConfig, err := sshClient.ReadFile(defaultSupervisorConfigPath)
+			if err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("reading default supervisor config error: %w", err)
+			}
+
 			// Clean /srv/app and clean /srv/conf/supervisor.d/app.conf because we don't want the default application
-			cmd := "/bin/sh -c 'rm -rf /srv/app/* && rm -rf /srv/conf/supervisor.d/app.conf && supervisorctl reread && supervisorctl update'"
+			cmd := "/bin/sh -c 'rm -rf /srv/app/* && rm -f " + defaultSupervisorConfigPath + " && supervisorctl reread && supervisorctl update'"
 			buf, err := sshClient.Run(cmd)
 			if err != nil {
 				cYellow.Print("Command '")
@@ -282,6 +328,7 @@ func commandUp(c *cli.Context) error {
 				fmt.Println(buf.String())
 				return err
 			}
+			printSupervisorChanges(supervisor.Diff(string(defaultConfig), ""))
 		}
 	}
 
@@ -291,9 +338,15 @@ func commandUp(c *cli.Context) error {
 		return fmt.Errorf("GetAppStatus error: %v", err)
 	}
 
+	err = validateTechnology(rostifile, status.Techs, newApp.Image)
+	if err != nil {
+		return err
+	}
+
 	var buf *bytes.Buffer
 
-	if status.PrimaryTech.Name != rostifile.Technology || (status.PrimaryTech.Version != rostifile.TechnologyVersion && rostifile.TechnologyVersion != "") {
+	// Binary applications don't need any runtime environment
+	if rostifile.Technology != parser.TechnologyBinary && technologyChanged(status, rostifile) {
 		cYellow.Print(".. technology change detected, settings up ")
 		cWhite.Print(rostifile.Technology)
 		cYellow.Println(" environment")
@@ -316,12 +369,8 @@ func commandUp(c *cli.Context) error {
 	// Initial commands
 	if appCreated || c.Bool("force-init") {
 		for _, cmd := range rostifile.InitialCommands {
-			buf, err := sshClient.Run(cmd)
+			err = runHook(sshClient, cmd)
 			if err != nil {
-				fmt.Print("Command '")
-				cWhite.Print(cmd)
-				cYellow.Println("' error:")
-				cRed.Println(buf.String())
 				return err
 			}
 		}
@@ -329,33 +378,64 @@ func commandUp(c *cli.Context) error {
 
 	// Deploy files
 	cYellow.Println(".. creating an archive")
-	err = createArchive(rostifile.SourcePath, "/tmp/_archive.tar", rostifile.Exclude) // TODO: create a proper temporary file here
+	// Every deploy has its own archive so parallel deploys of a workspace don't overwrite each other's
+	archiveFile, err := ioutil.TempFile("", "rostictl-*.tar")
+	if err != nil {
+		return fmt.Errorf("creating temporary file error: %w", err)
+	}
+	archivePath := archiveFile.Name()
+	archiveFile.Close()
+	defer os.Remove(archivePath)
+
+	err = createArchive(rostifile.ArchivePath(), archivePath, rostifile.Exclude)
 	if err != nil {
 		return err
 	}
 
 	cYellow.Println(".. copying archive into the container")
-	archive, err := os.Open("/tmp/_archive.tar")
+	archive, err := os.Open(archivePath)
 	if err != nil {
 		return err
 	}
 	defer archive.Close()
 
-	err = sshClient.StreamFile("/srv/_archive.tar", archive)
+	archiveInfo, err := archive.Stat()
+	if err != nil {
+		return err
+	}
+
+	var progressOutput io.Writer
+	if !c.Bool("quiet") {
+		progressOutput = os.Stdout
+	}
+	upload := progress.NewReader(archive, archiveInfo.Size(), progressOutput, terminal.IsTerminal(int(os.Stdout.Fd())))
+	upload.Limit = rateLimit
+
+	err = sshClient.StreamFile("/srv/_archive.tar", upload)
+	upload.Finish()
+	if err != nil {
+		return err
+	}
+
+	// Environment variables
+	err = writeEnvFile(sshClient, rostifile)
 	if err != nil {
 		return err
 	}
 
 	// Before commands
 	for _, cmd := range rostifile.BeforeCommands {
-		cYellow.Print(".. running command:")
-		cWhite.Println(cmd)
-		buf, err = sshClient.Run("/bin/sh -c '" + cmd + "'")
+		err = runHook(sshClient, cmd)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Backup for the case the health check fails
+	if rostifile.Healthcheck != nil && rostifile.Healthcheck.Rollback {
+		cYellow.Println(".. backing up current code")
+		err = backupCode(sshClient)
 		if err != nil {
-			fmt.Print("Command '")
-			cWhite.Print(cmd)
-			cYellow.Println("' error:")
-			cRed.Println(buf.String())
 			return err
 		}
 	}
@@ -370,71 +450,52 @@ func commandUp(c *cli.Context) error {
 		return err
 	}
 
-	// Setup crontab
-	if len(rostifile.Crontabs) > 0 {
-		cYellow.Println(".. setting up crontabs")
-		err = sshClient.SendFile("/srv/conf/crontab", strings.Join(rostifile.Crontabs, "\n")+"\n")
+	// The uploaded code could have overwritten .env file
+	err = writeEnvFile(sshClient, rostifile)
+	if err != nil {
+		return err
+	}
+
+	// Files defined in Rostifile
+	if len(rostifile.Files) > 0 {
+		cYellow.Println(".. uploading files")
+		err = uploadFiles(sshClient, rostifile)
 		if err != nil {
-			return fmt.Errorf("uploading crontabs error: %w", err)
+			return err
 		}
-		_, err = sshClient.Run("crontab /srv/conf/crontab")
-		if err != nil {
-			return fmt.Errorf("refreshing crontab error: %w", err)
-		}
-	}
-
-	// Setup background processes
-	if len(rostifile.Processes) > 0 {
-		cYellow.Println(".. setting up supervisor processes")
-		var processes []string
-		for _, process := range rostifile.Processes {
-			processTemplate := `[program:` + process.Name + `]
-command=` + process.Command + `
-environment=PATH="/srv/bin/primary_tech:/usr/local/bin:/usr/bin:/bin:/srv/.npm-packages/bin"
-autostart=true
-autorestart=true
-directory=/srv/app
-process_name=` + process.Name + `
-stdout_logfile=/srv/log/` + process.Name + `.log
-stdout_logfile_maxbytes=2MB
-stdout_logfile_backups=5
-stdout_capture_maxbytes=2MB
-stdout_events_enabled=false
-redirect_stderr=true
-`
-			if process.StopKillAsGroup {
-				processTemplate += "stopasgroup=true\n"
-				processTemplate += "killasgroup=true\n"
-			}
+	}
 
-			processes = append(processes, processTemplate)
-		}
+	// Setup crontab, it runs even without any jobs so the removed ones are cleared
+	cYellow.Println(".. setting up crontabs")
+	err = updateCrontab(sshClient, rostifile)
+	if err != nil {
+		return err
+	}
 
-		err = sshClient.SendFile("/srv/conf/supervisor.d/rostictl.conf", "# This file is gonna be rewritten by rostictl\n\n"+strings.Join(processes, "\n")+"\n")
-		if err != nil {
-			return fmt.Errorf("updating supervisor config error: %w", err)
-		}
+	// Setup background processes, empty config is written too so removed processes are stopped
+	cYellow.Println(".. setting up supervisor processes")
+	changes, err := updateSupervisor(sshClient, rostifile)
+	if err != nil {
+		return err
+	}
+	printSupervisorChanges(changes)
 
-		_, err = sshClient.Run("supervisorctl reread")
-		if err != nil {
-			return fmt.Errorf("refreshing supervisor error: %w", err)
-		}
-		_, err = sshClient.Run("supervisorctl update")
+	// Setup after commands
+	for _, cmd := range rostifile.AfterCommands {
+		err = runHook(sshClient, cmd)
 		if err != nil {
-			return fmt.Errorf("updating supervisor error: %w", err)
+			return err
 		}
 	}
 
-	// Setup after commands
-	for _, cmd := range rostifile.AfterCommands {
-		cYellow.Print(".. running command:")
-		fmt.Printf(" %s\n", cmd)
-		buf, err = sshClient.Run("/bin/sh -c '" + cmd + "'")
+	// Check the processes have started
+	if len(rostifile.Processes) > 0 && c.Int("process-timeout") > 0 {
+		cYellow.Println(".. waiting for supervisor processes to start")
+		err = waitForProcesses(sshClient, rostifile, time.Duration(c.Int("process-timeout"))*time.Second, c.Int("log-lines"))
 		if err != nil {
-			cRed.Println("error: ", err)
-			cRed.Println(buf.String())
 			return err
 		}
+		cGreen.Println("     all processes are running")
 	}
 
 	// Done
@@ -453,14 +514,56 @@ redirect_stderr=true
 		return err
 	}
 
+	// Health check
+	if rostifile.Healthcheck != nil {
+		rostifile.Healthcheck.SetDefaults()
+
+		cYellow.Println(".. checking health of the application")
+		checker := healthcheck.Checker{
+			URLs:         healthcheckURLs(rostifile, app.Domains),
+			Status:       rostifile.Healthcheck.Status,
+			BodyContains: rostifile.Healthcheck.BodyContains,
+			Timeout:      time.Duration(rostifile.Healthcheck.Timeout) * time.Second,
+			Retries:      rostifile.Healthcheck.Retries,
+			Interval:     time.Duration(rostifile.Healthcheck.Interval) * time.Second,
+			AppStatus: func() (bool, error) {
+				status, err := client.GetAppStatus(appState.ApplicationID)
+				return status.HTTPStatus, err
+			},
+			OnFailure: func(attempt int, err error) {
+				cGrey.Printf("     attempt %d/%d: %s\n", attempt, rostifile.Healthcheck.Retries, err.Error())
+			},
+		}
+
+		err = checker.Run()
+		if err != nil {
+			cRed.Println(".. the application is not healthy")
+
+			if rostifile.Healthcheck.Rollback {
+				cYellow.Println(".. restoring previous code")
+				rollbackErr := restoreCode(sshClient, rostifile)
+				if rollbackErr != nil {
+					cRed.Println(rollbackErr.Error())
+				} else {
+					cGreen.Println(".. previous code restored")
+				}
+			}
+
+			return fmt.Errorf("health check failed: %w", err)
+		}
+		cGreen.Println(".. the application is healthy")
+	}
+
 	fmt.Println("")
 	printAppStatus(app.Domains, status, app, false)
 
-	fmt.Println("")
-	fmt.Println("Note: This output doesn't have to be precise, because container")
-	fmt.Println("hasn't had to boot up fully or DNS hasn't propagated into the world.")
-	fmt.Println("Run `rostictl status` to run these checks again later to find out what's")
-	fmt.Println("the status of this application.")
+	if rostifile.Healthcheck == nil {
+		fmt.Println("")
+		fmt.Println("Note: This output doesn't have to be precise, because container")
+		fmt.Println("hasn't had to boot up fully or DNS hasn't propagated into the world.")
+		fmt.Println("Run `rostictl status` to run these checks again later to find out what's")
+		fmt.Println("the status of this application.")
+	}
 
 	return nil
 }
@@ -624,10 +727,18 @@ func commandPlans(c *cli.Context) error {
 		return err
 	}
 
-	cGrey.Printf("\n  %12s  Plan\n", "Slug")
-	cGrey.Printf("  %12s  ------------\n", "------------")
+	cGrey.Printf("\n  %-12s  %-12s  %8s  %8s  %9s  %8s\n", "Slug", "Plan", "RAM", "Disk", "CPU quota", "Price")
+	cGrey.Printf("  %-12s  %-12s  %8s  %8s  %9s  %8s\n", "------------", "------------", "--------", "--------", "---------", "--------")
 	for _, plan := range plans {
-		fmt.Printf("  %21s  %s\n", cYellow.Sprint(strings.ToLower(plan.Name)), cGrey.Sprint(plan.Name))
+		fmt.Printf(
+			"  %s  %s  %8d  %8d  %9d  %8d\n",
+			cYellow.Sprintf("%-12s", strings.ToLower(plan.Name)),
+			cGrey.Sprintf("%-12s", plan.Name),
+			plan.RAM,
+			plan.Disk,
+			plan.CPUQuote,
+			plan.Price,
+		)
 	}
 	fmt.Println("")
 	fmt.Println("Note: Use slug in your Rostifile.")
@@ -691,9 +802,9 @@ func commandRuntimes(c *cli.Context) error {
 }
 
 func commandInit(c *cli.Context) error {
-	_, err := os.Stat("./Rostifile")
+	_, err := os.Stat(parser.FilePath())
 	if !os.IsNotExist(err) {
-		cRed.Println("Rostifile already exists in this directory")
+		cRed.Println(parser.FilePath() + " already exists")
 		os.Exit(1)
 	}
 
@@ -707,8 +818,8 @@ func commandInit(c *cli.Context) error {
 		return err
 	}
 
-	rostifile.AfterCommands = bits.AfterCommands
-	rostifile.BeforeCommands = bits.BeforeCommands
+	rostifile.AfterCommands = parser.NewCommands(bits.AfterCommands...)
+	rostifile.BeforeCommands = parser.NewCommands(bits.BeforeCommands...)
 	rostifile.Processes = bits.Processes
 	rostifile.AppPort = bits.AppPort
 	rostifile.Technology = bits.Technology
@@ -721,6 +832,615 @@ func commandInit(c *cli.Context) error {
 	return nil
 }
 
+func commandHistory(c *cli.Context) error {
+	var records []history.Record
+	var err error
+
+	if c.Bool("local") {
+		records, err = history.LoadLocal()
+		if err != nil {
+			return err
+		}
+	} else {
+		_, _, sshClient, err := connectApp()
+		if err != nil {
+			return err
+		}
+
+		cYellow.Println(".. loading deploy history")
+		body, err := sshClient.ReadFile(history.RemotePath)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("reading deploy history error: %w", err)
+		}
+		records = history.Decode(body)
+	}
+
+	limit := c.Int("limit")
+	if limit > 0 && len(records) > limit {
+		records = records[len(records)-limit:]
+	}
+
+	if c.Bool("json") {
+		body, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(body))
+		return nil
+	}
+
+	if len(records) == 0 {
+		fmt.Println("No deploys recorded yet.")
+		return nil
+	}
+
+	cGrey.Printf("\n  %-19s  %-12s  %-9s  %-8s  %-26s  %9s  %s\n", "Time", "User", "Commit", "Plan", "Runtime", "Duration", "Result")
+	cGrey.Printf("  %-19s  %-12s  %-9s  %-8s  %-26s  %9s  %s\n", "-------------------", "------------", "---------", "--------", "--------------------------", "---------", "-------")
+	for _, record := range records {
+		result := cGreen.Sprint(record.Result)
+		if record.Result != history.ResultSuccess {
+			result = cRed.Sprint(record.Result)
+		}
+
+		fmt.Printf(
+			"  %-19s  %-12s  %-9s  %-8s  %-26s  %8.1fs  %s\n",
+			record.Time.Local().Format("2006-01-02 15:04:05"),
+			record.User,
+			record.ShortCommit(),
+			record.Plan,
+			record.Runtime,
+			record.Duration,
+			result,
+		)
+	}
+	fmt.Println("")
+
+	return nil
+}
+
+// findCronJob returns cron job of the given name defined in Rostifile
+func findCronJob(rostifile *parser.Rostifile, name string) (parser.CronJob, error) {
+	for _, job := range rostifile.CronJobs() {
+		if job.Name == name {
+			return job, nil
+		}
+	}
+
+	return parser.CronJob{}, fmt.Errorf("cron job %s not found in Rostifile", name)
+}
+
+func commandCronList(c *cli.Context) error {
+	rostifile, err := parser.Parse()
+	if err != nil {
+		return err
+	}
+
+	jobs := rostifile.CronJobs()
+	if len(jobs) == 0 {
+		fmt.Println("No cron jobs defined in Rostifile.")
+		return nil
+	}
+
+	cGrey.Printf("\n  %-20s  %-15s  %-7s  %s\n", "Name", "Schedule", "Timeout", "Command")
+	cGrey.Printf("  %-20s  %-15s  %-7s  %s\n", "--------------------", "---------------", "-------", "-------")
+	for _, job := range jobs {
+		timeout := "-"
+		if job.Timeout > 0 {
+			timeout = strconv.Itoa(job.Timeout) + "s"
+		}
+		fmt.Printf("  %-20s  %-15s  %-7s  %s\n", job.Name, job.Schedule, timeout, job.Command)
+	}
+	fmt.Println("")
+
+	return nil
+}
+
+func commandCronRun(c *cli.Context) error {
+	if c.Args().Len() != 1 {
+		return errors.New("name of the cron job is required")
+	}
+
+	rostifile, err := parser.Parse()
+	if err != nil {
+		return err
+	}
+
+	job, err := findCronJob(rostifile, c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	_, _, sshClient, err := connectApp()
+	if err != nil {
+		return err
+	}
+
+	cYellow.Print(".. running cron job: ")
+	cWhite.Println(job.Name)
+
+	buf, err := sshClient.Run(crontab.Command(job))
+	fmt.Print(buf.String())
+	if err != nil {
+		return fmt.Errorf("cron job %s error: %w", job.Name, err)
+	}
+
+	cGreen.Println(".. all done")
+
+	return nil
+}
+
+func commandCronLogs(c *cli.Context) error {
+	if c.Args().Len() != 1 {
+		return errors.New("name of the cron job is required")
+	}
+
+	rostifile, err := parser.Parse()
+	if err != nil {
+		return err
+	}
+
+	job, err := findCronJob(rostifile, c.Args().First())
+	if err != nil {
+		return err
+	}
+	if job.Raw != "" {
+		return fmt.Errorf("cron job %s is defined as a crontab line and its output is not logged by rostictl", job.Name)
+	}
+
+	_, _, sshClient, err := connectApp()
+	if err != nil {
+		return err
+	}
+
+	buf, err := sshClient.Run("tail -n " + strconv.Itoa(c.Int("lines")) + " " + shell.Quote(job.LogPath()))
+	if err != nil {
+		return fmt.Errorf("reading log of cron job %s error: %w", job.Name, err)
+	}
+	fmt.Print(buf.String())
+
+	return nil
+}
+
+func commandLockStatus(c *cli.Context) error {
+	_, _, sshClient, err := connectApp()
+	if err != nil {
+		return err
+	}
+
+	deployLock, err := lock.Status(sshClient)
+	if err != nil {
+		return err
+	}
+
+	if deployLock == nil {
+		cGreen.Println("The application is not locked.")
+		return nil
+	}
+
+	cYellow.Print("The application is locked by ")
+	cWhite.Println(deployLock.String())
+
+	return nil
+}
+
+func commandUnlock(c *cli.Context) error {
+	_, _, sshClient, err := connectApp()
+	if err != nil {
+		return err
+	}
+
+	deployLock, err := lock.Status(sshClient)
+	if err != nil {
+		return err
+	}
+
+	if deployLock == nil {
+		cGreen.Println("The application is not locked.")
+		return nil
+	}
+
+	if !deployLock.IsMine() && !c.Bool("force") {
+		return fmt.Errorf("the lock is held by %s, use --force to remove it anyway", deployLock.String())
+	}
+
+	err = lock.ForceRelease(sshClient)
+	if err != nil {
+		return err
+	}
+
+	cGreen.Println(".. lock removed")
+
+	return nil
+}
+
+func commandEnvList(c *cli.Context) error {
+	rostifile, err := parser.Parse()
+	if err != nil {
+		return err
+	}
+
+	printEnv := func(title string, env map[string]string) {
+		cYellow.Println(title)
+		if len(env) == 0 {
+			cGrey.Println("  (no variables)")
+		}
+		for _, key := range sortedKeys(env) {
+			fmt.Printf("  %s=%s\n", cWhite.Sprint(key), env[key])
+		}
+		fmt.Println("")
+	}
+
+	fmt.Println("")
+	if c.String("process") == "" {
+		printEnv("Global:", rostifile.Env)
+	}
+	for _, process := range rostifile.Processes {
+		if c.String("process") == "" || c.String("process") == process.Name {
+			printEnv("Process "+process.Name+":", process.Env)
+		}
+	}
+
+	return nil
+}
+
+func commandEnvSet(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return errors.New("no variable given, use KEY=VALUE format")
+	}
+
+	variables := make(map[string]string, c.NArg())
+	for _, arg := range c.Args().Slice() {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid variable %s, use KEY=VALUE format", arg)
+		}
+		variables[parts[0]] = parts[1]
+	}
+
+	errs := parser.ValidateEnv(variables)
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	return updateEnv(c, func(env map[string]string) map[string]string {
+		if env == nil {
+			env = make(map[string]string, len(variables))
+		}
+		for key, value := range variables {
+			env[key] = value
+		}
+		return env
+	})
+}
+
+func commandEnvUnset(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return errors.New("no variable given")
+	}
+
+	return updateEnv(c, func(env map[string]string) map[string]string {
+		for _, key := range c.Args().Slice() {
+			delete(env, key)
+		}
+		return env
+	})
+}
+
+// updateEnv changes environment variables in Rostifile and applies them on
+// the application without uploading the code.
+func updateEnv(c *cli.Context, change func(map[string]string) map[string]string) error {
+	rostifile, err := parser.ParseRaw()
+	if err != nil {
+		return err
+	}
+
+	if c.String("process") == "" {
+		rostifile.Env = change(rostifile.Env)
+	} else {
+		var found bool
+		for i, process := range rostifile.Processes {
+			if process.Name == c.String("process") {
+				rostifile.Processes[i].Env = change(process.Env)
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("process %s not found in Rostifile", c.String("process"))
+		}
+	}
+
+	cYellow.Println(".. writing Rostifile")
+	err = parser.Write(*rostifile)
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("no-apply") {
+		cGreen.Println(".. all done")
+		return nil
+	}
+
+	// Variables are expanded only in the applied configuration, not in the written one
+	rostifile, err = parser.Parse()
+	if err != nil {
+		return err
+	}
+
+	// The structure is not written anymore so secrets can be decrypted now
+	err = decryptSecrets(rostifile)
+	if err != nil {
+		return err
+	}
+
+	_, _, sshClient, err := connectApp()
+	if err != nil {
+		return err
+	}
+
+	cYellow.Println(".. updating environment variables in the container")
+	err = writeEnvFile(sshClient, rostifile)
+	if err != nil {
+		return err
+	}
+
+	cYellow.Println(".. updating supervisor processes")
+	changes, err := updateSupervisor(sshClient, rostifile)
+	if err != nil {
+		return err
+	}
+	printSupervisorChanges(changes)
+
+	cGreen.Println(".. all done")
+
+	return nil
+}
+
+func commandSecretsList(c *cli.Context) error {
+	rostifile, err := parser.ParseRaw()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("")
+	cYellow.Println("Secrets:")
+	if len(rostifile.Secrets) == 0 {
+		cGrey.Println("  (no secrets)")
+	}
+	for _, name := range sortedKeys(rostifile.Secrets) {
+		fmt.Println("  " + name)
+	}
+	fmt.Println("")
+
+	return nil
+}
+
+func commandSecretsSet(c *cli.Context) error {
+	name := c.Args().Get(0)
+	if name == "" {
+		return errors.New("no secret name given")
+	}
+
+	errs := parser.ValidateEnv(map[string]string{name: ""})
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	var value string
+	if c.NArg() > 1 {
+		value = c.Args().Get(1)
+	} else if terminal.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Print("Value of " + name + ": ")
+		raw, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		if err != nil {
+			return fmt.Errorf("reading user input error: %w", err)
+		}
+		value = string(raw)
+	} else {
+		raw, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("reading standard input error: %w", err)
+		}
+		value = strings.TrimRight(string(raw), "\r\n")
+	}
+
+	key, err := loadSecretsKey(true)
+	if err != nil {
+		return err
+	}
+
+	encrypted, err := secrets.Encrypt(key, value)
+	if err != nil {
+		return err
+	}
+
+	rostifile, err := parser.ParseRaw()
+	if err != nil {
+		return err
+	}
+
+	if rostifile.Secrets == nil {
+		rostifile.Secrets = make(map[string]string, 1)
+	}
+	rostifile.Secrets[name] = encrypted
+
+	cYellow.Println(".. writing Rostifile")
+	err = parser.Write(*rostifile)
+	if err != nil {
+		return err
+	}
+
+	cGreen.Println(".. all done, the secret will be applied during the next deploy")
+
+	return nil
+}
+
+func commandSecretsGet(c *cli.Context) error {
+	name := c.Args().Get(0)
+	if name == "" {
+		return errors.New("no secret name given")
+	}
+
+	rostifile, err := parser.ParseRaw()
+	if err != nil {
+		return err
+	}
+
+	value, ok := rostifile.Secrets[name]
+	if !ok {
+		return fmt.Errorf("secret %s not found", name)
+	}
+
+	key, err := loadSecretsKey(false)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := secrets.Decrypt(key, value)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(plaintext)
+
+	return nil
+}
+
+func commandSecretsUnset(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return errors.New("no secret name given")
+	}
+
+	rostifile, err := parser.ParseRaw()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range c.Args().Slice() {
+		if _, ok := rostifile.Secrets[name]; !ok {
+			return fmt.Errorf("secret %s not found", name)
+		}
+		delete(rostifile.Secrets, name)
+	}
+
+	cYellow.Println(".. writing Rostifile")
+	err = parser.Write(*rostifile)
+	if err != nil {
+		return err
+	}
+
+	cGreen.Println(".. all done, the change will be applied during the next deploy")
+
+	return nil
+}
+
+func commandSecretsRotate(c *cli.Context) error {
+	rostifile, err := parser.ParseRaw()
+	if err != nil {
+		return err
+	}
+
+	oldKey, err := loadSecretsKey(false)
+	if err != nil {
+		return err
+	}
+
+	newKey, err := secrets.GenerateKey()
+	if err != nil {
+		return err
+	}
+
+	cYellow.Println(".. re-encrypting secrets")
+	for name, value := range rostifile.Secrets {
+		plaintext, err := secrets.Decrypt(oldKey, value)
+		if err != nil {
+			return fmt.Errorf("secret %s: %w", name, err)
+		}
+
+		rostifile.Secrets[name], err = secrets.Encrypt(newKey, plaintext)
+		if err != nil {
+			return err
+		}
+	}
+
+	if os.Getenv(secrets.KeyEnvVariable) != "" {
+		cYellow.Printf(".. the key is loaded from %s, update the variable to the new key:\n", secrets.KeyEnvVariable)
+		fmt.Println(newKey.String())
+	} else {
+		cYellow.Println(".. saving the new key, the old one is kept in " + secretsKeyPath() + ".old")
+		err = secrets.SaveKey(secretsKeyPath()+".old", oldKey)
+		if err != nil {
+			return err
+		}
+		err = secrets.SaveKey(secretsKeyPath(), newKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	cYellow.Println(".. writing Rostifile")
+	err = parser.Write(*rostifile)
+	if err != nil {
+		return err
+	}
+
+	cGreen.Println(".. all done, don't forget to share the new key with your team")
+
+	return nil
+}
+
+func commandValidate(c *cli.Context) error {
+	cYellow.Println(".. validating " + parser.FilePath())
+	_, report, err := parser.Check()
+	if err != nil {
+		return cli.Exit(err.Error(), 1)
+	}
+
+	printReport(report)
+
+	if !report.OK() {
+		return cli.Exit(fmt.Sprintf("%d error(s) and %d warning(s) found", len(report.Errors), len(report.Warnings)), 1)
+	}
+	if len(report.Warnings) > 0 && c.Bool("strict") {
+		return cli.Exit(fmt.Sprintf("%d warning(s) found", len(report.Warnings)), 2)
+	}
+
+	cGreen.Println("Rostifile is valid")
+
+	return nil
+}
+
+func commandRostifileMigrate(c *cli.Context) error {
+	cYellow.Println(".. migrating " + parser.FilePath())
+	applied, err := parser.MigrateFiles()
+	if err != nil {
+		return err
+	}
+
+	if len(applied) == 0 {
+		cGreen.Printf("Rostifile is already in the latest format version %d\n", parser.CurrentVersion)
+		return nil
+	}
+
+	for _, description := range applied {
+		fmt.Println("  " + description)
+	}
+	cGreen.Printf(".. Rostifile upgraded to format version %d\n", parser.CurrentVersion)
+
+	return nil
+}
+
+func commandSchema(c *cli.Context) error {
+	body, err := json.MarshalIndent(parser.Schema(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(body))
+
+	return nil
+}
+
 func commandVersion(c *cli.Context) error {
 	fmt.Println("Version:", version)
 	return nil
@@ -732,6 +1452,11 @@ func commandRostifile(c *cli.Context) error {
 		return err
 	}
 
+	// Secrets are encrypted but we don't want to print them anyway
+	for name := range rostifile.Secrets {
+		rostifile.Secrets[name] = "<hidden>"
+	}
+
 	fmt.Println("Rostifile:")
 	fmt.Println("----------")
 	body, err := yaml.Marshal(rostifile)
@@ -33,13 +33,31 @@ func main() {
 				Aliases: []string{"nocolor"},
 				Usage:   "Terminal output without colors",
 			},
+			&cli.StringFlag{
+				Name:    "file",
+				Aliases: []string{"f"},
+				EnvVars: []string{"ROSTIFILE"},
+				Usage:   "Path to Rostifile, by default it's searched in the current directory and its parents",
+			},
+			&cli.StringFlag{
+				Name:    "chdir",
+				Aliases: []string{"C"},
+				Usage:   "Changes working directory before doing anything else",
+			},
+			&cli.StringFlag{
+				Name:    "env",
+				Aliases: []string{"e"},
+				EnvVars: []string{"ROSTI_ENV"},
+				Usage:   "Selects environment, Rostifile.<env> or environments section of Rostifile is merged over Rostifile and .rosti.<env>.state is used",
+			},
 		},
-		Before: noColor,
+		Before: before,
 		Commands: []*cli.Command{
 			{
-				Name:    "up",
-				Aliases: []string{},
-				Usage:   "Deploys new or existing application",
+				Name:      "up",
+				Aliases:   []string{},
+				Usage:     "Deploys new or existing application",
+				UsageText: "rostictl up [options]\n\n     rostictl up [options] --all\n     rostictl up [options] APP [APP...]\n\n   The last two forms deploy apps defined in Rostiworkspace file in parallel.",
 				Flags: []cli.Flag{
 					&cli.IntFlag{
 						Name:    "company",
@@ -51,9 +69,57 @@ func main() {
 						Name:  "force-init",
 						Usage: "Runs initialization commands even if the application exists.",
 					},
+					&cli.IntFlag{
+						Name:  "process-timeout",
+						Value: 60,
+						Usage: "How many seconds to wait for supervisor processes to get into RUNNING state, 0 disables the check",
+					},
+					&cli.IntFlag{
+						Name:  "log-lines",
+						Value: 20,
+						Usage: "Number of log lines printed when a process fails to start",
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Prints what would change without changing anything (same as plan command)",
+					},
+					&cli.BoolFlag{
+						Name:    "quiet",
+						Aliases: []string{"q"},
+						Usage:   "Doesn't print progress of the upload",
+					},
+					&cli.StringFlag{
+						Name:  "limit-rate",
+						Usage: "Limits upload bandwidth to given bytes per second, K, M and G suffixes are supported (e.g. 2M or 500K)",
+					},
+					&cli.BoolFlag{
+						Name:  "all",
+						Usage: "Deploys all apps defined in Rostiworkspace file",
+					},
+					&cli.IntFlag{
+						Name:    "jobs",
+						Aliases: []string{"j"},
+						Value:   3,
+						Usage:   "Number of workspace apps deployed at the same time",
+					},
 				},
 				Action: commandUp,
 			},
+			{
+				Name:      "plan",
+				Aliases:   []string{"diff"},
+				Usage:     "Prints what would be changed by up command, exits with code 2 when there is a difference",
+				UsageText: "rostictl plan [--company ID]",
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:    "company",
+						Aliases: []string{"c"},
+						Value:   0,
+						Usage:   "Company ID",
+					},
+				},
+				Action: commandPlan,
+			},
 			{
 				Name:    "down",
 				Aliases: []string{"stop"},
@@ -107,6 +173,13 @@ func main() {
 				Aliases: []string{},
 				Usage:   "Read Rostifile into internal structure and encodes it again (for debugging)",
 				Action:  commandRostifile,
+				Subcommands: []*cli.Command{
+					{
+						Name:   "migrate",
+						Usage:  "Upgrades Rostifile and its environments to the latest format version, comments are kept",
+						Action: commandRostifileMigrate,
+					},
+				},
 			},
 			{
 
@@ -123,6 +196,167 @@ func main() {
 					},
 				},
 			},
+			{
+				Name:    "env",
+				Aliases: []string{},
+				Usage:   "Manages environment variables of the application",
+				Subcommands: []*cli.Command{
+					{
+						Name:    "list",
+						Aliases: []string{"ls"},
+						Usage:   "Prints environment variables defined in Rostifile",
+						Action:  commandEnvList,
+						Flags:   envFlags(),
+					},
+					{
+						Name:      "set",
+						Usage:     "Sets environment variables and applies them without uploading the code",
+						ArgsUsage: "KEY=VALUE [KEY=VALUE...]",
+						Action:    commandEnvSet,
+						Flags:     envFlags(),
+					},
+					{
+						Name:      "unset",
+						Usage:     "Removes environment variables and applies the change without uploading the code",
+						ArgsUsage: "KEY [KEY...]",
+						Action:    commandEnvUnset,
+						Flags:     envFlags(),
+					},
+				},
+			},
+			{
+				Name:    "secrets",
+				Aliases: []string{},
+				Usage:   "Manages encrypted secrets stored in Rostifile, they are passed into the application as environment variables",
+				Subcommands: []*cli.Command{
+					{
+						Name:    "list",
+						Aliases: []string{"ls"},
+						Usage:   "Prints names of the secrets",
+						Action:  commandSecretsList,
+					},
+					{
+						Name:      "set",
+						Usage:     "Encrypts a secret and saves it into Rostifile, the value is read from standard input when it's not given",
+						ArgsUsage: "NAME [VALUE]",
+						Action:    commandSecretsSet,
+					},
+					{
+						Name:      "get",
+						Usage:     "Prints decrypted value of the secret",
+						ArgsUsage: "NAME",
+						Action:    commandSecretsGet,
+					},
+					{
+						Name:      "unset",
+						Usage:     "Removes secrets from Rostifile",
+						ArgsUsage: "NAME [NAME...]",
+						Action:    commandSecretsUnset,
+					},
+					{
+						Name:   "rotate",
+						Usage:  "Generates a new key and re-encrypts all secrets with it",
+						Action: commandSecretsRotate,
+					},
+				},
+			},
+			{
+				Name:    "cron",
+				Aliases: []string{},
+				Usage:   "Manages cron jobs defined in Rostifile",
+				Subcommands: []*cli.Command{
+					{
+						Name:    "list",
+						Aliases: []string{"ls"},
+						Usage:   "Prints cron jobs defined in Rostifile",
+						Action:  commandCronList,
+					},
+					{
+						Name:      "run",
+						Usage:     "Runs the cron job in the container right now",
+						ArgsUsage: "NAME",
+						Action:    commandCronRun,
+					},
+					{
+						Name:      "logs",
+						Usage:     "Prints the end of the cron job's log",
+						ArgsUsage: "NAME",
+						Action:    commandCronLogs,
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:    "lines",
+								Aliases: []string{"n"},
+								Value:   50,
+								Usage:   "Number of lines to print",
+							},
+						},
+					},
+				},
+			},
+			{
+				Name:    "lock",
+				Aliases: []string{},
+				Usage:   "Manages the lock preventing concurrent deploys",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "status",
+						Usage:  "Prints who holds the deploy lock",
+						Action: commandLockStatus,
+					},
+				},
+			},
+			{
+				Name:    "unlock",
+				Aliases: []string{},
+				Usage:   "Removes the deploy lock, use it when a deploy was killed and left a stale lock",
+				Action:  commandUnlock,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "force",
+						Usage: "Removes the lock even when it's held by another user or host",
+					},
+				},
+			},
+			{
+				Name:    "history",
+				Aliases: []string{},
+				Usage:   "Prints history of deploys of the application",
+				Action:  commandHistory,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Prints the history in JSON format",
+					},
+					&cli.BoolFlag{
+						Name:  "local",
+						Usage: "Reads local copy of the history instead of the one stored on the server",
+					},
+					&cli.IntFlag{
+						Name:    "limit",
+						Aliases: []string{"n"},
+						Value:   20,
+						Usage:   "Number of last deploys to print, 0 means all of them",
+					},
+				},
+			},
+			{
+				Name:    "validate",
+				Aliases: []string{},
+				Usage:   "Checks Rostifile, exits with code 1 when there are errors and with 2 when there are warnings in strict mode",
+				Action:  commandValidate,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "strict",
+						Usage: "Treats warnings as errors",
+					},
+				},
+			},
+			{
+				Name:    "schema",
+				Aliases: []string{},
+				Usage:   "Prints JSON schema of Rostifile, it can be used by editors for autocompletion and validation",
+				Action:  commandSchema,
+			},
 			{
 				Name:    "version",
 				Aliases: []string{},
@@ -138,3 +372,18 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+// envFlags returns flags shared by env subcommands
+func envFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "process",
+			Aliases: []string{"p"},
+			Usage:   "Works with environment variables of the given process instead of the global ones",
+		},
+		&cli.BoolFlag{
+			Name:  "no-apply",
+			Usage: "Changes only Rostifile and doesn't update the application",
+		},
+	}
+}
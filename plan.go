@@ -0,0 +1,314 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/rosti-cz/cli/src/config"
+	"github.com/rosti-cz/cli/src/crontab"
+	"github.com/rosti-cz/cli/src/parser"
+	"github.com/rosti-cz/cli/src/rostiapi"
+	"github.com/rosti-cz/cli/src/state"
+	"github.com/rosti-cz/cli/src/supervisor"
+	"github.com/urfave/cli/v2"
+)
+
+// Exit code returned by the plan command when the application differs from Rostifile
+const driftExitCode = 2
+
+// planChange is a single difference between Rostifile and the deployed application
+type planChange struct {
+	Action  string // one of +, - and ~
+	Subject string
+	Detail  string
+}
+
+// deployPlan collects all differences found between Rostifile and the application
+type deployPlan struct {
+	Changes []planChange
+}
+
+func (d *deployPlan) add(action, subject, detail string) {
+	d.Changes = append(d.Changes, planChange{Action: action, Subject: subject, Detail: detail})
+}
+
+// addValue adds a change when the old and new values differ
+func (d *deployPlan) addValue(subject, oldValue, newValue string) {
+	if oldValue != newValue {
+		d.add("~", subject, fmt.Sprintf("%s -> %s", printableValue(oldValue), printableValue(newValue)))
+	}
+}
+
+// addLists adds a change for every added and removed item of a list
+func (d *deployPlan) addLists(subject string, oldValues, newValues []string) {
+	added, removed := diffLists(oldValues, newValues)
+	for _, value := range added {
+		d.add("+", subject, value)
+	}
+	for _, value := range removed {
+		d.add("-", subject, value)
+	}
+}
+
+func (d *deployPlan) print() {
+	fmt.Println("")
+	for _, change := range d.Changes {
+		line := fmt.Sprintf("  %s %s: %s", change.Action, change.Subject, change.Detail)
+		switch change.Action {
+		case "+":
+			cGreen.Println(line)
+		case "-":
+			cRed.Println(line)
+		default:
+			cYellow.Println(line)
+		}
+	}
+	if len(d.Changes) == 0 {
+		cGreen.Println("  No changes. The application matches the Rostifile.")
+	}
+	fmt.Println("")
+}
+
+func printableValue(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
+
+// diffLists returns items that are in newValues but not in oldValues and
+// items that are in oldValues but not in newValues.
+func diffLists(oldValues, newValues []string) ([]string, []string) {
+	var added, removed []string
+
+	oldSet := make(map[string]bool, len(oldValues))
+	for _, value := range oldValues {
+		oldSet[value] = true
+	}
+	newSet := make(map[string]bool, len(newValues))
+	for _, value := range newValues {
+		newSet[value] = true
+	}
+
+	for _, value := range newValues {
+		if !oldSet[value] {
+			added = append(added, value)
+		}
+	}
+	for _, value := range oldValues {
+		if !newSet[value] {
+			removed = append(removed, value)
+		}
+	}
+
+	return added, removed
+}
+
+// Prints what would change if the application was deployed, without changing anything
+func commandPlan(c *cli.Context) error {
+	config := config.Load()
+
+	cYellow.Println(".. loading Rostifile")
+	rostifile, err := parser.Parse()
+	if err != nil {
+		return err
+	}
+
+	// Secrets are needed to render the same configuration as up does
+	err = decryptSecrets(rostifile)
+	if err != nil {
+		return err
+	}
+
+	cYellow.Println(".. loading state file")
+	appState, err := state.Load()
+	if err != nil {
+		return err
+	}
+
+	client := rostiapi.Client{
+		Token:      config.Token,
+		CompanyID:  appState.CompanyID,
+		ExtraError: os.Stderr,
+	}
+
+	if appState.CompanyID == 0 {
+		cYellow.Println(".. loading list of your companies")
+		companyID, err := findCompany(&client, appState, c)
+		if err != nil {
+			return err
+		}
+		client.CompanyID = companyID
+	}
+
+	cYellow.Println(".. loading list of available plans")
+	plans, err := client.GetPlans()
+	if err != nil {
+		return err
+	}
+	var planID uint
+	if rostifile.Plan != "" {
+		planID, err = findPlanID(plans, rostifile.Plan)
+		if err != nil {
+			return err
+		}
+	}
+
+	selectedRuntime, err := selectRuntime(&client, rostifile)
+	if err != nil {
+		return err
+	}
+
+	supervisorConfig, err := supervisor.Render(rostifile)
+	if err != nil {
+		return err
+	}
+
+	plan := deployPlan{}
+
+	if appState.ApplicationID == 0 {
+		plan.add("+", "application", rostifile.Name)
+		if rostifile.Plan != "" {
+			plan.add("+", "plan", rostifile.Plan)
+		} else {
+			plan.add("+", "plan", "(default)")
+		}
+		plan.add("+", "runtime", selectedRuntime)
+		plan.add("+", "mode", appMode(rostifile))
+		for _, domain := range rostifile.Domains {
+			plan.add("+", "domain", domain)
+		}
+		if rostifile.Technology != "" {
+			plan.add("+", "technology", strings.TrimSpace(rostifile.Technology+" "+rostifile.TechnologyVersion))
+		}
+		for _, name := range supervisor.Diff("", supervisorConfig).Added {
+			plan.add("+", "program", name)
+		}
+		for _, line := range crontabJobs(rostifile) {
+			plan.add("+", "crontab", line)
+		}
+		for _, filePath := range rostifile.FilePaths() {
+			plan.add("+", "file", path.Clean(filePath))
+		}
+	} else {
+		cYellow.Println(".. loading current state of the application")
+		app, err := client.GetApp(appState.ApplicationID)
+		if err != nil {
+			return err
+		}
+
+		cYellow.Println(".. loading application status")
+		status, err := client.GetAppStatus(appState.ApplicationID)
+		if err != nil {
+			return fmt.Errorf("GetAppStatus error: %v", err)
+		}
+
+		plan.addValue("name", app.Name, rostifile.Name)
+		// Empty plan keeps the current one
+		if planID != 0 && planID != app.Plan {
+			var currentPlan string
+			for _, p := range plans {
+				if p.ID == app.Plan {
+					currentPlan = strings.ToLower(p.Name)
+				}
+			}
+			plan.add("~", "plan", fmt.Sprintf("%s -> %s", printableValue(currentPlan), rostifile.Plan))
+		}
+		plan.addValue("runtime", app.Image, selectedRuntime)
+		plan.addValue("mode", app.Mode, appMode(rostifile))
+		if len(rostifile.Domains) > 0 {
+			plan.addLists("domain", app.Domains, rostifile.Domains)
+		}
+
+		// Technologies of a different runtime are not known until the runtime is changed
+		if app.Image == selectedRuntime {
+			err = validateTechnology(rostifile, status.Techs, app.Image)
+			if err != nil {
+				return err
+			}
+		}
+
+		if rostifile.Technology != parser.TechnologyBinary && technologyChanged(status, rostifile) {
+			plan.add(
+				"~",
+				"technology",
+				fmt.Sprintf(
+					"%s -> %s",
+					printableValue(strings.TrimSpace(status.PrimaryTech.Name+" "+status.PrimaryTech.Version)),
+					printableValue(strings.TrimSpace(rostifile.Technology+" "+rostifile.TechnologyVersion)),
+				),
+			)
+		}
+
+		// Remote configuration files
+		err = ensureSSHKey(appState)
+		if err != nil {
+			return err
+		}
+
+		sshClient, err := sshClientForApp(&app, appState)
+		if err != nil {
+			return err
+		}
+
+		cYellow.Println(".. loading remote configuration")
+		remoteSupervisorConfig, err := sshClient.ReadFile(supervisor.ConfigPath)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("reading supervisor config error: %w", err)
+		}
+		currentCrontab, err := readCrontab(sshClient)
+		if err != nil {
+			return err
+		}
+
+		changes := supervisor.Diff(string(remoteSupervisorConfig), supervisorConfig)
+		for _, name := range changes.Added {
+			plan.add("+", "program", name)
+		}
+		for _, name := range changes.Changed {
+			plan.add("~", "program", name)
+		}
+		for _, name := range changes.Removed {
+			plan.add("-", "program", name)
+		}
+
+		plan.addLists("crontab", crontab.Block(currentCrontab), crontabJobs(rostifile))
+
+		for _, filePath := range rostifile.FilePaths() {
+			file := rostifile.Files[filePath]
+			content, err := file.Load()
+			if err != nil {
+				return err
+			}
+
+			remotePath := path.Clean(filePath)
+			remoteContent, err := sshClient.ReadFile(remotePath)
+			if os.IsNotExist(err) {
+				plan.add("+", "file", remotePath)
+			} else if err != nil {
+				return fmt.Errorf("reading file %s error: %w", remotePath, err)
+			} else if string(remoteContent) != content {
+				plan.add("~", "file", remotePath)
+			}
+		}
+	}
+
+	plan.print()
+
+	filesCount, err := countSourceFiles(rostifile.ArchivePath(), rostifile.Exclude)
+	if os.IsNotExist(err) && rostifile.BuildOutput != "" {
+		fmt.Println("Files to upload: unknown, build_output doesn't exist until build_commands run")
+	} else if err != nil {
+		return fmt.Errorf("scanning source directory error: %w", err)
+	} else {
+		fmt.Printf("Files to upload: %d\n", filesCount)
+	}
+
+	if len(plan.Changes) > 0 {
+		return cli.Exit(fmt.Sprintf("Plan: %d change(s) to apply.", len(plan.Changes)), driftExitCode)
+	}
+
+	return nil
+}
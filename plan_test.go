@@ -0,0 +1,14 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffLists(t *testing.T) {
+	added, removed := diffLists([]string{"a.cz", "b.cz"}, []string{"b.cz", "c.cz"})
+
+	assert.Equal(t, []string{"c.cz"}, added)
+	assert.Equal(t, []string{"a.cz"}, removed)
+}
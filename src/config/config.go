@@ -43,6 +43,12 @@ func initialChecks() {
 	}
 }
 
+// Directory returns path of the directory with configuration files of this tool
+func Directory() string {
+	initialChecks()
+	return configDirectory
+}
+
 // Config holds configuration of this tool
 type Config struct {
 	Token string `yaml:"token"`
@@ -0,0 +1,165 @@
+package crontab
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/rosti-cz/cli/src/parser"
+	"github.com/rosti-cz/cli/src/shell"
+	"github.com/rosti-cz/cli/src/supervisor"
+)
+
+/*
+This package manages a marked block inside user's crontab. Everything
+outside of the block is kept untouched so jobs added manually or by other
+tools survive the deploy.
+*/
+
+// Markers of the block managed by rostictl
+const (
+	BeginMarker = "# BEGIN rostictl"
+	EndMarker   = "# END rostictl"
+)
+
+// Command returns shell command running the job in the same environment
+// as the application processes. It's a single line because every line of
+// crontab is a separate entry.
+func Command(job parser.CronJob) string {
+	script := supervisor.ShellEnv + "; cd /srv/app || exit 1; " + job.Command
+
+	command := "/bin/sh -c " + shell.Quote(script)
+	if job.Timeout > 0 {
+		command = "timeout " + strconv.Itoa(job.Timeout) + " " + command
+	}
+
+	return command
+}
+
+// Line returns crontab line of the job. Jobs written as a plain crontab line
+// in Rostifile are kept as they are, the other ones log into their log file.
+func Line(job parser.CronJob) string {
+	if job.Raw != "" {
+		return job.Raw
+	}
+
+	command := Command(job) + " >> " + shell.Quote(job.LogPath()) + " 2>&1"
+	// % is a special character in crontab and it has to be escaped
+	command = strings.ReplaceAll(command, "%", "\\%")
+
+	return job.Schedule + " " + command
+}
+
+// splitLines returns lines of the content without the trailing empty ones
+func splitLines(content string) []string {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// HasBlock returns true if the crontab contains block managed by rostictl
+func HasBlock(content string) bool {
+	for _, line := range splitLines(content) {
+		if strings.TrimSpace(line) == BeginMarker {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Block returns lines of the block managed by rostictl
+func Block(content string) []string {
+	var block []string
+	var inside bool
+
+	for _, line := range splitLines(content) {
+		switch strings.TrimSpace(line) {
+		case BeginMarker:
+			inside = true
+		case EndMarker:
+			inside = false
+		default:
+			if inside && strings.TrimSpace(line) != "" {
+				block = append(block, line)
+			}
+		}
+	}
+
+	return block
+}
+
+// Merge replaces the block managed by rostictl with the given jobs. The
+// block is removed when there are no jobs.
+func Merge(content string, jobs []string) string {
+	var lines []string
+	var inside bool
+
+	for _, line := range splitLines(content) {
+		switch strings.TrimSpace(line) {
+		case BeginMarker:
+			inside = true
+			// Drop the empty line separating the block
+			if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+				lines = lines[:len(lines)-1]
+			}
+		case EndMarker:
+			inside = false
+		default:
+			if !inside {
+				lines = append(lines, line)
+			}
+		}
+	}
+
+	// Trailing empty lines would grow with every deploy
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if len(jobs) > 0 {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, BeginMarker)
+		lines = append(lines, jobs...)
+		lines = append(lines, EndMarker)
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Adopt removes jobs installed by older versions of rostictl, which replaced
+// the whole crontab, from a crontab without the managed block. The legacy
+// content is the crontab file written by the older version.
+func Adopt(content string, legacy string) string {
+	if HasBlock(content) || strings.TrimSpace(legacy) == "" {
+		return content
+	}
+
+	legacyLines := make(map[string]bool)
+	for _, line := range splitLines(legacy) {
+		if strings.TrimSpace(line) != "" {
+			legacyLines[line] = true
+		}
+	}
+
+	var lines []string
+	for _, line := range splitLines(content) {
+		if !legacyLines[line] {
+			lines = append(lines, line)
+		}
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,49 @@
+package crontab
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rosti-cz/cli/src/parser"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	existing := "MAILTO=admin@example.com\n0 1 * * * /srv/backup.sh\n"
+
+	merged := Merge(existing, []string{"*/15 * * * * date"})
+	assert.Equal(t, "MAILTO=admin@example.com\n0 1 * * * /srv/backup.sh\n\n# BEGIN rostictl\n*/15 * * * * date\n# END rostictl\n", merged)
+	assert.Equal(t, []string{"*/15 * * * * date"}, Block(merged))
+
+	// Second run replaces the block and keeps manual entries
+	merged = Merge(merged+"5 5 * * * /srv/manual.sh\n", []string{"0 * * * * uptime"})
+	assert.Equal(t, "MAILTO=admin@example.com\n0 1 * * * /srv/backup.sh\n5 5 * * * /srv/manual.sh\n\n# BEGIN rostictl\n0 * * * * uptime\n# END rostictl\n", merged)
+
+	// No jobs removes only the block
+	assert.Equal(t, "MAILTO=admin@example.com\n0 1 * * * /srv/backup.sh\n5 5 * * * /srv/manual.sh\n", Merge(merged, nil))
+	assert.Equal(t, "", Merge("# BEGIN rostictl\n* * * * * date\n# END rostictl\n", nil))
+}
+
+func TestAdopt(t *testing.T) {
+	legacy := "*/15 * * * * date\n"
+	existing := "*/15 * * * * date\n0 1 * * * /srv/backup.sh\n"
+
+	assert.Equal(t, "0 1 * * * /srv/backup.sh\n", Adopt(existing, legacy))
+
+	managed := Merge(existing, []string{"*/15 * * * * date"})
+	assert.Equal(t, managed, Adopt(managed, legacy))
+}
+
+func TestLine(t *testing.T) {
+	assert.Equal(t, "*/5 * * * * date", Line(parser.CronJob{Raw: "*/5 * * * * date", Schedule: "*/5 * * * *", Command: "date"}))
+
+	line := Line(parser.CronJob{Name: "report", Schedule: "@daily", Command: "date +%F", Timeout: 60})
+	assert.True(t, strings.HasPrefix(line, "@daily timeout 60 /bin/sh -c "))
+	assert.Contains(t, line, `date +\%F`)
+	assert.True(t, strings.HasSuffix(line, " >> '/srv/log/cron-report.log' 2>&1"))
+
+	// Every line of crontab is a separate entry
+	assert.NotContains(t, line, "\n")
+	assert.NotContains(t, Line(parser.CronJob{Name: "r", Schedule: "@daily", Command: "date"}), "\n")
+}
@@ -0,0 +1,110 @@
+package healthcheck
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+/*
+This package checks the application responds as expected after deploy.
+Every attempt requests all configured URLs and asks the API about the
+status of the application. The application is healthy when all of them
+pass in a single attempt.
+*/
+
+// Checker keeps configuration of the health check
+type Checker struct {
+	URLs         []string      // URLs requested in every attempt
+	Status       int           // Expected HTTP status code
+	BodyContains string        // Text that has to be included in the response body
+	Timeout      time.Duration // Timeout of a single request
+	Retries      int           // Number of attempts
+	Interval     time.Duration // Pause between attempts
+	// AppStatus is called in every attempt when it's set. The application is
+	// healthy only when it returns true.
+	AppStatus func() (bool, error)
+	// OnFailure is called after every failed attempt when it's set
+	OnFailure func(attempt int, err error)
+}
+
+// CheckURL requests the URL and checks its response
+func (c *Checker) CheckURL(url string) error {
+	client := &http.Client{
+		Timeout: c.Timeout,
+	}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return fmt.Errorf("%s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != c.Status {
+		return fmt.Errorf("%s: expected status %d, got %d", url, c.Status, resp.StatusCode)
+	}
+
+	if c.BodyContains != "" {
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("%s: reading body error: %w", url, err)
+		}
+
+		if !strings.Contains(string(body), c.BodyContains) {
+			return fmt.Errorf("%s: response doesn't contain \"%s\"", url, c.BodyContains)
+		}
+	}
+
+	return nil
+}
+
+func (c *Checker) check() error {
+	for _, url := range c.URLs {
+		err := c.CheckURL(url)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.AppStatus != nil {
+		ok, err := c.AppStatus()
+		if err != nil {
+			return fmt.Errorf("application status error: %w", err)
+		}
+		if !ok {
+			return errors.New("application status reports HTTP problem")
+		}
+	}
+
+	return nil
+}
+
+// Run checks the application until it's healthy or until it runs out of attempts.
+// The error from the last attempt is returned.
+func (c *Checker) Run() error {
+	retries := c.Retries
+	if retries < 1 {
+		retries = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= retries; attempt++ {
+		err = c.check()
+		if err == nil {
+			return nil
+		}
+
+		if c.OnFailure != nil {
+			c.OnFailure(attempt, err)
+		}
+
+		if attempt < retries {
+			time.Sleep(c.Interval)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,81 @@
+package healthcheck
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckerRun(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, "status: ok")
+	}))
+	defer server.Close()
+
+	var failures int
+	checker := Checker{
+		URLs:         []string{server.URL + "/health"},
+		Status:       200,
+		BodyContains: "ok",
+		Timeout:      time.Second,
+		Retries:      5,
+		Interval:     time.Millisecond,
+		OnFailure: func(attempt int, err error) {
+			failures++
+		},
+	}
+
+	err := checker.Run()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, requests)
+	assert.Equal(t, 2, failures)
+}
+
+func TestCheckerRunFails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "maintenance")
+	}))
+	defer server.Close()
+
+	checker := Checker{
+		URLs:         []string{server.URL},
+		Status:       200,
+		BodyContains: "ok",
+		Timeout:      time.Second,
+		Retries:      2,
+		Interval:     time.Millisecond,
+	}
+
+	err := checker.Run()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "doesn't contain")
+}
+
+func TestCheckerAppStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	checker := Checker{
+		URLs:     []string{server.URL},
+		Status:   200,
+		Timeout:  time.Second,
+		Retries:  2,
+		Interval: time.Millisecond,
+		AppStatus: func() (bool, error) {
+			return false, nil
+		},
+	}
+
+	err := checker.Run()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,118 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"time"
+)
+
+// RemotePath is location of the deploy history on the server
+const RemotePath = "/srv/.rosti/deploys.jsonl"
+
+var localHistoryFilePath = "./.rosti.history"
+
+// SetEnvironment switches to the local history of the given environment
+func SetEnvironment(name string) {
+	if name == "" {
+		localHistoryFilePath = "./.rosti.history"
+	} else {
+		localHistoryFilePath = "./.rosti." + name + ".history"
+	}
+}
+
+// NewRecord returns a record of deploy that starts right now.
+func NewRecord() *Record {
+	record := Record{
+		Time: time.Now(),
+	}
+
+	currentUser, err := user.Current()
+	if err == nil {
+		record.User = currentUser.Username
+	}
+
+	record.Host, _ = os.Hostname()
+
+	return &record
+}
+
+// Finish sets duration and result of the deploy based on the returned error.
+func (r *Record) Finish(err error) {
+	r.Duration = time.Since(r.Time).Seconds()
+	if err != nil {
+		r.Result = ResultFailure
+		r.Error = err.Error()
+	} else {
+		r.Result = ResultSuccess
+	}
+}
+
+// Encode returns the record as one line of JSON including the trailing new line.
+func (r *Record) Encode() (string, error) {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return "", fmt.Errorf("deploy record encoding error: %w", err)
+	}
+
+	return string(body) + "\n", nil
+}
+
+// Decode parses JSON lines with deploy records. Lines that cannot be parsed are skipped.
+func Decode(body []byte) []Record {
+	records := []Record{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		record := Record{}
+		err := json.Unmarshal(line, &record)
+		if err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+
+	return records
+}
+
+// AppendLocal appends the record into the local copy of the deploy history.
+func AppendLocal(record *Record) error {
+	line, err := record.Encode()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(localHistoryFilePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("local deploy history writing error: %w", err)
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(line)
+	if err != nil {
+		return fmt.Errorf("local deploy history writing error: %w", err)
+	}
+
+	return nil
+}
+
+// LoadLocal returns records from the local copy of the deploy history.
+func LoadLocal() ([]Record, error) {
+	body, err := ioutil.ReadFile(localHistoryFilePath)
+	if os.IsNotExist(err) {
+		return []Record{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("local deploy history reading error: %w", err)
+	}
+
+	return Decode(body), nil
+}
@@ -0,0 +1,44 @@
+package history
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecode(t *testing.T) {
+	record := NewRecord()
+	record.Commit = "abc"
+	record.Finish(nil)
+	line, err := record.Encode()
+	assert.Nil(t, err)
+
+	// Malformed and empty lines are skipped
+	records := Decode([]byte(line + "\n{broken\nnot json\n" + line))
+	assert.Len(t, records, 2)
+	assert.Equal(t, "abc", records[0].Commit)
+	assert.Equal(t, ResultSuccess, records[1].Result)
+
+	assert.Equal(t, []Record{}, Decode(nil))
+}
+
+func TestFinish(t *testing.T) {
+	record := &Record{Time: time.Now().Add(-2 * time.Second)}
+	record.Finish(nil)
+	assert.Equal(t, ResultSuccess, record.Result)
+	assert.Empty(t, record.Error)
+	assert.True(t, record.Duration >= 2)
+
+	record.Finish(errors.New("SSH daemon has not started in time"))
+	assert.Equal(t, ResultFailure, record.Result)
+	assert.Equal(t, "SSH daemon has not started in time", record.Error)
+}
+
+func TestShortCommit(t *testing.T) {
+	assert.Equal(t, "-", (&Record{}).ShortCommit())
+	assert.Equal(t, "-*", (&Record{Dirty: true}).ShortCommit())
+	assert.Equal(t, "abc", (&Record{Commit: "abc"}).ShortCommit())
+	assert.Equal(t, "0123abcd*", (&Record{Commit: "0123abcdef456789", Dirty: true}).ShortCommit())
+}
@@ -0,0 +1,42 @@
+package history
+
+import "time"
+
+// Possible results of a deploy
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// Record describes one deploy of the application.
+type Record struct {
+	Time          time.Time `json:"time"`
+	User          string    `json:"user"`
+	Host          string    `json:"host"`
+	AppID         uint      `json:"app_id,omitempty"`
+	Commit        string    `json:"commit,omitempty"`
+	Dirty         bool      `json:"dirty,omitempty"`
+	RostifileHash string    `json:"rostifile_hash,omitempty"`
+	Runtime       string    `json:"runtime,omitempty"`
+	Plan          string    `json:"plan,omitempty"`
+	Duration      float64   `json:"duration"` // in seconds
+	Result        string    `json:"result"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// ShortCommit returns first eight characters of the commit hash followed by
+// a star when the working tree was dirty.
+func (r *Record) ShortCommit() string {
+	commit := r.Commit
+	if len(commit) > 8 {
+		commit = commit[:8]
+	}
+	if commit == "" {
+		commit = "-"
+	}
+	if r.Dirty {
+		commit += "*"
+	}
+
+	return commit
+}
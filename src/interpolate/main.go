@@ -0,0 +1,127 @@
+package interpolate
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+/*
+This package expands variables in strings the way shell does. Supported forms
+are ${VAR} and ${VAR:-default}, where default is used when the variable is
+not set or it's empty. $${ is written as a literal ${ so shell variables in
+scripts can be escaped.
+*/
+
+// Lookup returns value of the variable and whether it's set
+type Lookup func(name string) (string, bool)
+
+var variableRegexp = regexp.MustCompile(`\$?\$\{([a-zA-Z_][a-zA-Z0-9_]*)(:-[^}]*)?\}`)
+
+// Expand replaces variables in the string. Names of variables without
+// a default that are not set are returned as missing.
+func Expand(s string, lookup Lookup) (string, []string) {
+	var missing []string
+
+	expanded := variableRegexp.ReplaceAllStringFunc(s, func(match string) string {
+		// Escaped variable
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+
+		parts := variableRegexp.FindStringSubmatch(match)
+		value, ok := lookup(parts[1])
+		if ok && value != "" {
+			return value
+		}
+		if parts[2] != "" {
+			return strings.TrimPrefix(parts[2], ":-")
+		}
+		if !ok {
+			missing = append(missing, parts[1])
+		}
+
+		return value
+	})
+
+	return expanded, missing
+}
+
+// Escape returns the string with all variables escaped, so Expand returns
+// the original string.
+func Escape(s string) string {
+	return variableRegexp.ReplaceAllStringFunc(s, func(match string) string {
+		return "$" + match
+	})
+}
+
+// Struct expands variables in all string fields of the structure the pointer
+// points to, including strings in nested structures, slices and maps. Fields
+// tagged with `interpolate:"-"` are skipped. Sorted names of missing variables
+// are returned.
+func Struct(pointer interface{}, lookup Lookup) []string {
+	missingSet := make(map[string]bool)
+	walk(reflect.ValueOf(pointer), lookup, missingSet)
+
+	missing := make([]string, 0, len(missingSet))
+	for name := range missingSet {
+		missing = append(missing, name)
+	}
+	sort.Strings(missing)
+
+	return missing
+}
+
+func walk(value reflect.Value, lookup Lookup, missing map[string]bool) {
+	expand := func(s string) string {
+		expanded, names := Expand(s, lookup)
+		for _, name := range names {
+			missing[name] = true
+		}
+		return expanded
+	}
+
+	switch value.Kind() {
+	case reflect.Ptr:
+		if !value.IsNil() {
+			walk(value.Elem(), lookup, missing)
+		}
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if field.PkgPath != "" || field.Tag.Get("interpolate") == "-" {
+				continue
+			}
+			walk(value.Field(i), lookup, missing)
+		}
+	case reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			walk(value.Index(i), lookup, missing)
+		}
+	case reflect.Map:
+		if value.IsNil() {
+			return
+		}
+		keys := value.MapKeys()
+		for _, key := range keys {
+			// Values of maps are not addressable so they are copied, changed and stored back
+			item := reflect.New(value.Type().Elem()).Elem()
+			item.Set(value.MapIndex(key))
+			walk(item, lookup, missing)
+
+			newKey := key
+			if key.Kind() == reflect.String {
+				newKey = reflect.ValueOf(expand(key.String())).Convert(key.Type())
+				if newKey.String() != key.String() {
+					value.SetMapIndex(key, reflect.Value{})
+				}
+			}
+			value.SetMapIndex(newKey, item)
+		}
+	case reflect.String:
+		if value.CanSet() {
+			value.SetString(expand(value.String()))
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package interpolate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func lookup(name string) (string, bool) {
+	values := map[string]string{"APP": "web", "EMPTY": ""}
+	value, ok := values[name]
+	return value, ok
+}
+
+func TestExpand(t *testing.T) {
+	for input, expected := range map[string]string{
+		"${APP}.rostiapp.cz":     "web.rostiapp.cz",
+		"${MISSING:-default}":    "default",
+		"${EMPTY:-default}":      "default",
+		"${APP:-}":               "web",
+		"echo $${HOME} $HOME":    "echo ${HOME} $HOME",
+		"no variables ${ here }": "no variables ${ here }",
+	} {
+		value, missing := Expand(input, lookup)
+		assert.Equal(t, expected, value, input)
+		assert.Empty(t, missing, input)
+	}
+
+	value, missing := Expand("${A}-${APP}-${B}", lookup)
+	assert.Equal(t, "-web-", value)
+	assert.Equal(t, []string{"A", "B"}, missing)
+}
+
+func TestStruct(t *testing.T) {
+	type nested struct {
+		Script string
+	}
+	value := struct {
+		Name    string
+		Domains []string
+		Files   map[string]nested
+		Env     map[string]string
+		Secret  string `interpolate:"-"`
+		Count   int
+	}{
+		Name:    "${APP}",
+		Domains: []string{"${APP}.example.com"},
+		Files:   map[string]nested{"/srv/${APP}.conf": {Script: "${MISSING}"}},
+		Env:     map[string]string{"A": "${APP:-x}"},
+		Secret:  "${APP}",
+	}
+
+	missing := Struct(&value, lookup)
+	assert.Equal(t, []string{"MISSING"}, missing)
+	assert.Equal(t, "web", value.Name)
+	assert.Equal(t, []string{"web.example.com"}, value.Domains)
+	assert.Equal(t, map[string]nested{"/srv/web.conf": {Script: ""}}, value.Files)
+	assert.Equal(t, map[string]string{"A": "web"}, value.Env)
+	assert.Equal(t, "${APP}", value.Secret)
+}
+
+func TestEscape(t *testing.T) {
+	for _, input := range []string{
+		"echo ${HOME}",
+		"echo $${HOME} ${PORT:-8080}",
+		"${1} ${VAR#x} $HOME",
+	} {
+		value, missing := Expand(Escape(input), lookup)
+		assert.Equal(t, input, value)
+		assert.Empty(t, missing)
+	}
+	assert.Equal(t, "echo $${HOME}", Escape("echo ${HOME}"))
+}
@@ -0,0 +1,153 @@
+package lock
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/rosti-cz/cli/src/shell"
+)
+
+/*
+This package implements an exclusive deploy lock stored on the server. The
+lock file is created with noclobber set so only one of two concurrent
+deploys can succeed.
+*/
+
+// Path is location of the lock file on the server
+const Path = "/srv/.rosti/deploy.lock"
+
+// ErrLocked is returned when the lock is held by someone else
+var ErrLocked = errors.New("another deploy is in progress")
+
+// Runner runs shell commands on the server, it's implemented by ssh.Client
+type Runner interface {
+	Run(command string) (*bytes.Buffer, error)
+}
+
+// Lock describes who holds the deploy lock
+type Lock struct {
+	Owner string    `json:"owner"`
+	Host  string    `json:"host"`
+	PID   int       `json:"pid"`
+	Time  time.Time `json:"time"`
+}
+
+// New returns lock owned by the current process
+func New() Lock {
+	lock := Lock{
+		PID:  os.Getpid(),
+		Time: time.Now().UTC().Truncate(time.Second),
+	}
+
+	currentUser, err := user.Current()
+	if err == nil {
+		lock.Owner = currentUser.Username
+	}
+	lock.Host, _ = os.Hostname()
+
+	return lock
+}
+
+// String returns human readable description of the lock
+func (l *Lock) String() string {
+	return fmt.Sprintf(
+		"%s@%s (PID %d) since %s (%s ago)",
+		l.Owner,
+		l.Host,
+		l.PID,
+		l.Time.Local().Format("2006-01-02 15:04:05"),
+		time.Since(l.Time).Round(time.Second),
+	)
+}
+
+// IsMine returns true if the lock was taken by the current user on this host
+func (l *Lock) IsMine() bool {
+	current := New()
+	return l.Owner == current.Owner && l.Host == current.Host
+}
+
+func (l *Lock) encode() (string, error) {
+	body, err := json.Marshal(l)
+	if err != nil {
+		return "", fmt.Errorf("lock encoding error: %w", err)
+	}
+
+	return string(body), nil
+}
+
+// Acquire creates the lock file atomically. When the lock is held by someone
+// else it returns ErrLocked together with the current lock.
+func Acquire(runner Runner, lock Lock) (*Lock, error) {
+	body, err := lock.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = runner.Run("mkdir -p /srv/.rosti && (set -C; printf '%s\\n' " + shell.Quote(body) + " > " + Path + ") 2>/dev/null")
+	if err == nil {
+		return &lock, nil
+	}
+
+	current, statusErr := Status(runner)
+	if statusErr != nil {
+		return nil, fmt.Errorf("acquiring deploy lock error: %w", err)
+	}
+	if current == nil {
+		// The lock was released in the meantime
+		return nil, fmt.Errorf("acquiring deploy lock error: %w", err)
+	}
+
+	return current, ErrLocked
+}
+
+// Release removes the lock file only if it still belongs to the given lock
+func Release(runner Runner, lock Lock) error {
+	body, err := lock.encode()
+	if err != nil {
+		return err
+	}
+
+	_, err = runner.Run("[ \"$(cat " + Path + " 2>/dev/null)\" != " + shell.Quote(body) + " ] || rm -f " + Path)
+	if err != nil {
+		return fmt.Errorf("releasing deploy lock error: %w", err)
+	}
+
+	return nil
+}
+
+// Status returns the current lock or nil if there is none
+func Status(runner Runner) (*Lock, error) {
+	buf, err := runner.Run("[ ! -f " + Path + " ] || cat " + Path)
+	if err != nil {
+		return nil, fmt.Errorf("reading deploy lock error: %w", err)
+	}
+
+	content := strings.TrimSpace(buf.String())
+	if content == "" {
+		return nil, nil
+	}
+
+	lock := Lock{}
+	err = json.Unmarshal([]byte(content), &lock)
+	if err != nil {
+		return nil, fmt.Errorf("deploy lock parsing error: %w", err)
+	}
+
+	return &lock, nil
+}
+
+// ForceRelease removes the lock file regardless of its owner
+func ForceRelease(runner Runner) error {
+	_, err := runner.Run("rm -f " + Path)
+	if err != nil {
+		return fmt.Errorf("removing deploy lock error: %w", err)
+	}
+
+	return nil
+}
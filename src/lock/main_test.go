@@ -0,0 +1,61 @@
+package lock
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRunner simulates the lock file on the server
+type fakeRunner struct {
+	content string
+}
+
+func (f *fakeRunner) Run(command string) (*bytes.Buffer, error) {
+	switch {
+	case strings.Contains(command, "set -C"):
+		if f.content != "" {
+			return bytes.NewBufferString(""), errors.New("exit status 1")
+		}
+		f.content = strings.Split(command, "'")[3]
+	case strings.HasPrefix(command, "[ ! -f"):
+		return bytes.NewBufferString(f.content), nil
+	case strings.HasPrefix(command, "rm -f"):
+		f.content = ""
+	case strings.Contains(command, "|| rm -f"):
+		if strings.Contains(command, "'"+f.content+"'") {
+			f.content = ""
+		}
+	}
+
+	return bytes.NewBufferString(""), nil
+}
+
+func TestAcquire(t *testing.T) {
+	runner := &fakeRunner{}
+
+	first := New()
+	_, err := Acquire(runner, first)
+	assert.Nil(t, err)
+
+	second := New()
+	second.PID = first.PID + 1
+	current, err := Acquire(runner, second)
+	assert.Equal(t, ErrLocked, err)
+	assert.Equal(t, first.PID, current.PID)
+	assert.Equal(t, first.Owner, current.Owner)
+
+	// Only the owner can release the lock
+	assert.Nil(t, Release(runner, second))
+	current, err = Status(runner)
+	assert.Nil(t, err)
+	assert.NotNil(t, current)
+
+	assert.Nil(t, Release(runner, first))
+	current, err = Status(runner)
+	assert.Nil(t, err)
+	assert.Nil(t, current)
+}
@@ -0,0 +1,81 @@
+package docgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+/*
+This package extracts comments of struct fields from Go source code. The
+parser package uses it to generate descriptions for its JSON schema.
+*/
+
+// Extract returns comments of struct fields in the Go file. Keys are in
+// Type.Field format.
+func Extract(filename string) (map[string]string, error) {
+	fileSet := token.NewFileSet()
+	file, err := parser.ParseFile(fileSet, filename, nil, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s error: %w", filename, err)
+	}
+
+	docs := make(map[string]string)
+	ast.Inspect(file, func(node ast.Node) bool {
+		typeSpec, ok := node.(*ast.TypeSpec)
+		if !ok {
+			return true
+		}
+		structType, ok := typeSpec.Type.(*ast.StructType)
+		if !ok {
+			return false
+		}
+
+		for _, field := range structType.Fields.List {
+			comment := field.Doc
+			if comment == nil {
+				comment = field.Comment
+			}
+			if comment == nil {
+				continue
+			}
+
+			text := strings.Join(strings.Fields(comment.Text()), " ")
+			for _, name := range field.Names {
+				if ast.IsExported(name.Name) {
+					docs[typeSpec.Name.Name+"."+name.Name] = text
+				}
+			}
+		}
+
+		return false
+	})
+
+	return docs, nil
+}
+
+// Generate returns formatted Go source of a file with the docs in a map
+// variable of the given name
+func Generate(pkg, variable, source string, docs map[string]string) ([]byte, error) {
+	keys := make([]string, 0, len(docs))
+	for key := range docs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by gendocs.go from %s; DO NOT EDIT.\n\n", source)
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	fmt.Fprintf(&buf, "var %s = map[string]string{\n", variable)
+	for _, key := range keys {
+		fmt.Fprintf(&buf, "%q: %q,\n", key, docs[key])
+	}
+	fmt.Fprintf(&buf, "}\n")
+
+	return format.Source(buf.Bytes())
+}
@@ -0,0 +1,63 @@
+// Code generated by gendocs.go from types.go; DO NOT EDIT.
+
+package parser
+
+var fieldDocs = map[string]string{
+	"Command.Env":                 "Additional environment variables of the script",
+	"Command.IgnoreErrors":        "Continue the deploy even if the script fails",
+	"Command.Script":              "Shell script, it can have multiple lines",
+	"Command.Timeout":             "Timeout in seconds, zero means no timeout",
+	"Command.Workdir":             "Directory where the script runs",
+	"CronJob.Command":             "Shell command",
+	"CronJob.Log":                 "Path of the log file. Default is /srv/log/cron-<name>.log.",
+	"CronJob.Name":                "Name of the job, used in the name of its log file",
+	"CronJob.Raw":                 "Raw is the original line when the job is written as a string",
+	"CronJob.Schedule":            "Schedule in the standard cron format, for example \"*/15 * * * *\" or \"@daily\"",
+	"CronJob.Timeout":             "Timeout in seconds, zero means no timeout",
+	"File.Content":                "Content of the file",
+	"File.Mode":                   "Permissions of the file in octal notation. Default is 0644.",
+	"File.Source":                 "Local file which content is uploaded instead of content",
+	"Healthcheck.BodyContains":    "Text that has to be included in the response body",
+	"Healthcheck.Interval":        "Pause between two attempts in seconds. Default is 5.",
+	"Healthcheck.Path":            "Path requested on every domain of the application. Default is /.",
+	"Healthcheck.Retries":         "Number of attempts before the deploy is considered as failed. Default is 12.",
+	"Healthcheck.Rollback":        "Restore the previous code when the check fails",
+	"Healthcheck.Status":          "Expected HTTP status code. Default is 200.",
+	"Healthcheck.Timeout":         "Timeout of a single request in seconds. Default is 10.",
+	"Process.AutoRestart":         "Restart policy, possible values are: true,false,unexpected. Default is true.",
+	"Process.Command":             "Command running the process, it has to stay in foreground",
+	"Process.Directory":           "Working directory of the process. Default is /srv/app.",
+	"Process.Env":                 "Environment variables of the process, they override the global ones",
+	"Process.LogBackups":          "Number of rotated log files to keep. Default is 5.",
+	"Process.LogMaxBytes":         "Maximum size of the log file before it's rotated, for example 2MB. Default is 2MB.",
+	"Process.Name":                "Name of the process, it can contain only a-zA-Z0-9_",
+	"Process.Numprocs":            "Number of instances of the process. Default is 1.",
+	"Process.Priority":            "Order of starting and stopping, lower priority starts first and stops last. Default is 999.",
+	"Process.StartSecs":           "How many seconds the process has to stay up to be considered as started. Default is 1.",
+	"Process.StopKillAsGroup":     "Send the stop signal to the whole process group",
+	"Process.StopSignal":          "Signal used to stop the process, possible values are: TERM,HUP,INT,QUIT,KILL,USR1,USR2. Default is TERM.",
+	"Process.StopWaitSecs":        "How many seconds to wait for the process to stop before it's killed. Default is 10.",
+	"Rostifile.AfterCommands":     "Commands to run after deploy ends.",
+	"Rostifile.AppPort":           "Application port that can be changed only during creation of the application. Default is 8080. No effect for PHP apps.",
+	"Rostifile.BeforeCommands":    "Commands to run before deploy begins.",
+	"Rostifile.BuildCommands":     "Commands to run locally in source_path before the code is archived and uploaded",
+	"Rostifile.BuildOutput":       "Directory inside source_path that is uploaded instead of the whole source_path, usually output of build_commands",
+	"Rostifile.Crontabs":          "Crontab jobs, either lines in the standard crontab format or structures with name, schedule, command, timeout and log",
+	"Rostifile.Domains":           "List of domains configured on the load balancer for this application",
+	"Rostifile.Env":               "Environment variables of all processes and deploy commands, also written into /srv/app/.env",
+	"Rostifile.Environments":      "Overlays merged over the Rostifile when an environment is selected with --env, key is name of the environment",
+	"Rostifile.Exclude":           "What directories and files to exclude from the deploy",
+	"Rostifile.Files":             "Map of files where key is path to the file (including /srv) and value is content of the file or structure with content, source and mode fields.",
+	"Rostifile.HTTPS":             "Enable/Disable HTTPS for all domains",
+	"Rostifile.Healthcheck":       "Check of the application done after deploy, the deploy fails when the application is not healthy",
+	"Rostifile.InitialCommands":   "Commmands to runs when the application is created",
+	"Rostifile.Name":              "Unique name of the application, it can contain only a-zA-Z0-9._",
+	"Rostifile.Plan":              "Plan of the service, possible values are: static,start,start+,normal,normal+,pro,pro+,business,business+. Default is defined in the backend.",
+	"Rostifile.Processes":         "List of background processes running in supervisor",
+	"Rostifile.Runtime":           "Runtime image of the application, default is defined in the backend, usually the latest.",
+	"Rostifile.Secrets":           "Encrypted environment variables managed by secrets command, they are decrypted during deploy",
+	"Rostifile.SourcePath":        "Directory with the source code that will be uploaded onto server into /srv/app. Default is .",
+	"Rostifile.Technology":        "Primary technology configured in application's container",
+	"Rostifile.TechnologyVersion": "Version of the primary technology",
+	"Rostifile.Version":           "Version of Rostifile format",
+}
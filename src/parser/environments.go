@@ -0,0 +1,199 @@
+package parser
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"regexp"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+/*
+Environments allow to deploy the same code into multiple applications, for
+example staging and production. An environment is an overlay merged over the
+base Rostifile. It's defined either in Rostifile.<name> file or in the
+environments section of Rostifile. Maps are merged recursively, other values
+including lists are replaced and null removes the key.
+*/
+
+var environmentNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Name of the selected environment, empty for the base Rostifile
+var environment string
+
+// SetEnvironment selects environment used by Parse, Write and Checksum
+func SetEnvironment(name string) error {
+	if name != "" && !environmentNameRegexp.MatchString(name) {
+		return fmt.Errorf("environment name %s can contain only these characters: a-zA-Z0-9_-", name)
+	}
+
+	environment = name
+	return nil
+}
+
+// Environment returns name of the selected environment
+func Environment() string {
+	return environment
+}
+
+// environmentFilePath returns path of the overlay file of the selected environment
+func environmentFilePath() string {
+	return rostiFilePath + "." + environment
+}
+
+// hasEnvironmentFile returns true if the selected environment has its own file
+func hasEnvironmentFile() bool {
+	_, err := os.Stat(environmentFilePath())
+	return err == nil
+}
+
+// applyEnvironment merges overlay of the selected environment over the base
+// Rostifile and returns the result. Version is the original version of
+// Rostifile, overlay files are written in the same version.
+func applyEnvironment(body []byte, version int) ([]byte, error) {
+	base := make(map[interface{}]interface{})
+	err := yaml.Unmarshal(body, &base)
+	if err != nil {
+		return nil, errors.Wrap(err, "Rostifile parsing error")
+	}
+
+	sections, _ := base["environments"].(map[interface{}]interface{})
+	delete(base, "environments")
+
+	section, found := sections[environment]
+	if found {
+		overlay, ok := section.(map[interface{}]interface{})
+		if !ok && section != nil {
+			return nil, fmt.Errorf("environment %s in Rostifile has to be a map", environment)
+		}
+		base = mergeMaps(base, overlay)
+	}
+
+	if hasEnvironmentFile() {
+		found = true
+
+		overlayBody, err := ioutil.ReadFile(environmentFilePath())
+		if err != nil {
+			return nil, errors.Wrap(err, environmentFilePath()+" reading error")
+		}
+		overlayBody, err = migrateOverlay(overlayBody, version)
+		if err != nil {
+			return nil, errors.Wrap(err, environmentFilePath()+" migration error")
+		}
+		overlay := make(map[interface{}]interface{})
+		err = yaml.Unmarshal(overlayBody, &overlay)
+		if err != nil {
+			return nil, errors.Wrap(err, environmentFilePath()+" parsing error")
+		}
+		base = mergeMaps(base, overlay)
+	}
+
+	if !found {
+		return nil, fmt.Errorf("environment %s is not defined, create %s or add it into environments section of Rostifile", environment, environmentFilePath())
+	}
+
+	return yaml.Marshal(base)
+}
+
+// mergeMaps merges overlay over base recursively
+func mergeMaps(base, overlay map[interface{}]interface{}) map[interface{}]interface{} {
+	merged := make(map[interface{}]interface{}, len(base))
+	for key, value := range base {
+		merged[key] = value
+	}
+
+	for key, value := range overlay {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+
+		baseMap, baseIsMap := merged[key].(map[interface{}]interface{})
+		overlayMap, overlayIsMap := value.(map[interface{}]interface{})
+		if baseIsMap && overlayIsMap {
+			merged[key] = mergeMaps(baseMap, overlayMap)
+		} else {
+			merged[key] = value
+		}
+	}
+
+	return merged
+}
+
+// diffMaps returns overlay that gives changed when merged over base
+func diffMaps(base, changed map[interface{}]interface{}) map[interface{}]interface{} {
+	overlay := make(map[interface{}]interface{})
+
+	for key, value := range changed {
+		baseMap, baseIsMap := base[key].(map[interface{}]interface{})
+		changedMap, changedIsMap := value.(map[interface{}]interface{})
+		if baseIsMap && changedIsMap {
+			if nested := diffMaps(baseMap, changedMap); len(nested) > 0 {
+				overlay[key] = nested
+			}
+		} else if !reflect.DeepEqual(base[key], value) {
+			overlay[key] = value
+		}
+	}
+
+	for key := range base {
+		if _, ok := changed[key]; !ok {
+			overlay[key] = nil
+		}
+	}
+
+	return overlay
+}
+
+// toMap converts the value into generic map as it would be parsed from YAML
+func toMap(value interface{}) (map[interface{}]interface{}, error) {
+	body, err := yaml.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[interface{}]interface{})
+	err = yaml.Unmarshal(body, &result)
+	return result, err
+}
+
+// writeEnvironment saves differences between the base Rostifile and the
+// given one as overlay of the selected environment. The base is not changed.
+func writeEnvironment(rostifile Rostifile) error {
+	body, err := ioutil.ReadFile(rostiFilePath)
+	if err != nil {
+		return errors.Wrap(err, "Rostifile reading error")
+	}
+	body, _, _, err = Migrate(body)
+	if err != nil {
+		return err
+	}
+
+	base := Rostifile{}
+	err = yaml.Unmarshal(body, &base)
+	if err != nil {
+		return errors.Wrap(err, "Rostifile parsing error")
+	}
+	base.Environments = nil
+
+	baseMap, err := toMap(base)
+	if err != nil {
+		return err
+	}
+	rostifile.Environments = nil
+	changedMap, err := toMap(rostifile)
+	if err != nil {
+		return err
+	}
+	overlay := diffMaps(baseMap, changedMap)
+
+	// The overlay file takes precedence, so changes go there when it exists
+	if hasEnvironmentFile() {
+		return writeNodes(environmentFilePath(), overlay)
+	}
+
+	return writeNodes(rostiFilePath, overlay, "environments", environment)
+}
@@ -0,0 +1,66 @@
+package parser
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestMergeMaps(t *testing.T) {
+	base := make(map[interface{}]interface{})
+	overlay := make(map[interface{}]interface{})
+	assert.Nil(t, yaml.Unmarshal([]byte("name: app\ndomains: [a.cz, b.cz]\nenv:\n  A: \"1\"\n  B: \"2\"\n"), &base))
+	assert.Nil(t, yaml.Unmarshal([]byte("name: app-staging\ndomains: [staging.a.cz]\nenv:\n  A: null\n  C: \"3\"\n"), &overlay))
+
+	merged := mergeMaps(base, overlay)
+	assert.Equal(t, "app-staging", merged["name"])
+	assert.Equal(t, []interface{}{"staging.a.cz"}, merged["domains"])
+	assert.Equal(t, map[interface{}]interface{}{"B": "2", "C": "3"}, merged["env"])
+
+	// Diff gives back an overlay producing the same result
+	assert.Equal(t, merged, mergeMaps(base, diffMaps(base, merged)))
+	assert.Equal(t, overlay, diffMaps(base, merged))
+}
+
+func TestWriteEnvironment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rostifile")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, FileName)
+	original := "version: 2\nname: app # base\nenv:\n  A: \"1\"\nenvironments:\n  staging:\n    name: app-staging\n"
+	assert.Nil(t, ioutil.WriteFile(path, []byte(original), 0644))
+	assert.Nil(t, ioutil.WriteFile(path+".production", []byte("name: app-production\n"), 0644))
+	SetFilePath(path)
+	defer SetFilePath("./" + FileName)
+	defer SetEnvironment("")
+
+	// Only the section of the environment is changed
+	assert.Nil(t, SetEnvironment("staging"))
+	rostifile, err := ParseRaw()
+	assert.Nil(t, err)
+	rostifile.Env["FOO"] = "bar"
+	assert.Nil(t, Write(*rostifile))
+
+	body, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "version: 2\nname: app # base\nenv:\n  A: \"1\"\nenvironments:\n  staging:\n    name: app-staging\n    env:\n      FOO: bar\n", string(body))
+
+	// Overlay file is changed and the base stays as it is
+	assert.Nil(t, SetEnvironment("production"))
+	rostifile, err = ParseRaw()
+	assert.Nil(t, err)
+	rostifile.Env["FOO"] = "baz"
+	assert.Nil(t, Write(*rostifile))
+
+	overlay, err := ioutil.ReadFile(path + ".production")
+	assert.Nil(t, err)
+	assert.Equal(t, "name: app-production\nenv:\n  FOO: baz\n", string(overlay))
+	again, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, body, again)
+}
@@ -0,0 +1,30 @@
+//go:build ignore
+// +build ignore
+
+// This program generates docs.go with comments of Rostifile's fields used
+// as descriptions in the JSON schema. Run it with go generate.
+package main
+
+import (
+	"io/ioutil"
+	"log"
+
+	"github.com/rosti-cz/cli/src/parser/docgen"
+)
+
+func main() {
+	docs, err := docgen.Extract("types.go")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	body, err := docgen.Generate("parser", "fieldDocs", "types.go", docs)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = ioutil.WriteFile("docs.go", body, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
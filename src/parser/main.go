@@ -1,37 +1,121 @@
 package parser
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 
 	"github.com/pkg/errors"
+	"github.com/rosti-cz/cli/src/interpolate"
 	"gopkg.in/yaml.v2"
 )
 
-const rostiFilePath = "./Rostifile"
+// FileName is the default name of Rostifile
+const FileName = "Rostifile"
 
-// Parse returns parsed Rostifile
+var rostiFilePath = "./" + FileName
+
+// SetFilePath changes path of Rostifile used by all functions of this package
+func SetFilePath(path string) {
+	rostiFilePath = path
+}
+
+// Source of variables expanded in Rostifile
+var lookup interpolate.Lookup = os.LookupEnv
+
+// SetLookup changes source of variables expanded in Rostifile
+func SetLookup(l interpolate.Lookup) {
+	lookup = l
+}
+
+// FilePath returns path of Rostifile
+func FilePath() string {
+	return rostiFilePath
+}
+
+// Parse returns parsed Rostifile with expanded variables
 func Parse() (*Rostifile, error) {
+	rostifile, missing, err := parse(true)
+	if err != nil {
+		return rostifile, err
+	}
+	if len(missing) > 0 {
+		return rostifile, fmt.Errorf("variables used in Rostifile are not set: %s", strings.Join(missing, ", "))
+	}
+
+	return rostifile, nil
+}
+
+// ParseRaw returns parsed Rostifile without expanding variables, use it
+// when the Rostifile is going to be written back.
+func ParseRaw() (*Rostifile, error) {
+	rostifile, _, err := parse(false)
+	return rostifile, err
+}
+
+// parse returns parsed Rostifile and names of variables that are not set
+func parse(expand bool) (*Rostifile, []string, error) {
 	rostifile := Rostifile{}
-	rostifile.Validate()
 
-	body, err := ioutil.ReadFile(rostiFilePath)
+	body, version, err := readBody()
 	if err != nil {
-		return &rostifile, errors.Wrap(err, "Rostifile reading error")
+		return &rostifile, nil, err
 	}
 
 	err = yaml.Unmarshal(body, &rostifile)
 	if err != nil {
-		return &rostifile, errors.Wrap(err, "Rostifile parsing error")
+		return &rostifile, nil, errors.Wrap(err, "Rostifile parsing error")
+	}
+
+	// Raw Rostifile is written back so it can't contain anything that's not in the file
+	var missing []string
+	if expand {
+		missing = interpolate.Struct(&rostifile, lookup)
+		rostifile.SetDefaults()
+
+		// Older Rostifiles are migrated with escaped variables but local source
+		// files of their files are not templates
+		if version >= variablesVersion {
+			for filePath, file := range rostifile.Files {
+				file.template = true
+				rostifile.Files[filePath] = file
+			}
+		}
+	}
+
+	return &rostifile, missing, nil
+}
+
+// readBody returns content of Rostifile migrated to the current version with
+// overlay of the selected environment. Original version of the file is
+// returned too.
+func readBody() ([]byte, int, error) {
+	body, err := ioutil.ReadFile(rostiFilePath)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "Rostifile reading error")
+	}
+
+	body, version, _, err := Migrate(body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if environment != "" {
+		body, err = applyEnvironment(body, version)
+		if err != nil {
+			return nil, 0, err
+		}
 	}
 
-	return &rostifile, nil
+	return body, version, nil
 }
 
 // Init create a new Rostifile in the current working directory
 func Init() (Rostifile, error) {
-	rostifile := Rostifile{}
+	rostifile := Rostifile{Version: CurrentVersion}
 
 	fmt.Print("Name of the project: ")
 	fmt.Scanln(&rostifile.Name)
@@ -57,13 +141,33 @@ func Init() (Rostifile, error) {
 	return rostifile, nil
 }
 
-// Write Rostifile
+// Write Rostifile. Comments and order of keys in the existing file are kept.
+// When an environment is selected only the differences against the base
+// Rostifile are saved into the environment's overlay.
 func Write(rostifile Rostifile) error {
-	body, err := yaml.Marshal(rostifile)
+	if environment != "" {
+		return writeEnvironment(rostifile)
+	}
+
+	return writeNodes(rostiFilePath, rostifile)
+}
+
+// Checksum returns SHA256 hash of the Rostifile's content
+func Checksum() (string, error) {
+	body, err := ioutil.ReadFile(rostiFilePath)
 	if err != nil {
-		return err
+		return "", errors.Wrap(err, "Rostifile reading error")
+	}
+
+	// Changes of the overlay change the deployed configuration too
+	if environment != "" && hasEnvironmentFile() {
+		overlayBody, err := ioutil.ReadFile(environmentFilePath())
+		if err != nil {
+			return "", errors.Wrap(err, environmentFilePath()+" reading error")
+		}
+		body = append(append(body, []byte("\n---\n")...), overlayBody...)
 	}
 
-	err = ioutil.WriteFile(rostiFilePath, body, 0644)
-	return err
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
 }
@@ -0,0 +1,31 @@
+package parser
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRawWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rostifile")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, FileName)
+	assert.Nil(t, ioutil.WriteFile(path, []byte("version: 2\nname: test\nenv:\n  A: \"1\"\n"), 0644))
+	SetFilePath(path)
+	defer SetFilePath("./" + FileName)
+
+	// Defaults are not written into the file
+	rostifile, err := ParseRaw()
+	assert.Nil(t, err)
+	rostifile.Env["B"] = "2"
+	assert.Nil(t, Write(*rostifile))
+
+	body, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "version: 2\nname: test\nenv:\n  A: \"1\"\n  B: \"2\"\n", string(body))
+}
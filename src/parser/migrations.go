@@ -0,0 +1,281 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rosti-cz/cli/src/interpolate"
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+/*
+Rostifile format is versioned. When the format changes, a migration is added
+here and CurrentVersion is increased. Older Rostifiles are migrated in memory
+every time they are parsed and "rostictl rostifile migrate" upgrades them on
+the disk. Migrations work with YAML nodes so comments are kept.
+*/
+
+// CurrentVersion is the latest version of Rostifile format
+const CurrentVersion = 2
+
+// Variables in Rostifile are expanded since this version
+const variablesVersion = 2
+
+// Rostifiles without version are considered as version 0
+const initialVersion = 0
+
+// migration upgrades Rostifile into the given version
+type migration struct {
+	version     int
+	description string
+	// apply changes the mapping node with Rostifile or with overlay of an environment
+	apply func(node *yaml3.Node) error
+}
+
+var migrations = []migration{
+	{
+		version:     1,
+		description: "format version added",
+		// Migrate writes the version itself, nothing else has changed
+		apply: func(node *yaml3.Node) error { return nil },
+	},
+	{
+		version:     variablesVersion,
+		description: "${ escaped as $${ because variables are expanded since version 2",
+		apply:       escapeVariables,
+	},
+}
+
+// escapeVariables escapes variables in all keys and values except secrets,
+// which are not expanded. Environments are migrated separately.
+func escapeVariables(node *yaml3.Node) error {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		switch node.Content[i].Value {
+		case "secrets", "environments":
+			continue
+		}
+		escapeNode(node.Content[i])
+		escapeNode(node.Content[i+1])
+	}
+
+	return nil
+}
+
+// escapeNode escapes variables in the node and all its children
+func escapeNode(node *yaml3.Node) {
+	if node.Kind == yaml3.ScalarNode && node.ShortTag() == "!!str" {
+		node.Value = interpolate.Escape(node.Value)
+	}
+	for _, child := range node.Content {
+		escapeNode(child)
+	}
+}
+
+// mappingValue returns value of the key in the mapping node or nil
+func mappingValue(node *yaml3.Node, key string) *yaml3.Node {
+	if node == nil || node.Kind != yaml3.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// decodeDocument parses the content into YAML node and returns its root
+// mapping, nil is returned for an empty document
+func decodeDocument(body []byte) (*yaml3.Node, *yaml3.Node, error) {
+	document := &yaml3.Node{}
+	err := yaml3.Unmarshal(body, document)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(document.Content) == 0 {
+		return document, nil, nil
+	}
+
+	root := document.Content[0]
+	if root.Kind != yaml3.MappingNode {
+		return nil, nil, errors.New("Rostifile has to be a map")
+	}
+
+	return document, root, nil
+}
+
+// encodeDocument returns YAML content of the node
+func encodeDocument(document *yaml3.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml3.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	err := encoder.Encode(document)
+	if err != nil {
+		return nil, err
+	}
+	err = encoder.Close()
+
+	return buf.Bytes(), err
+}
+
+// documentVersion returns format version of Rostifile
+func documentVersion(root *yaml3.Node) (int, error) {
+	node := mappingValue(root, "version")
+	if node == nil {
+		return initialVersion, nil
+	}
+
+	version, err := strconv.Atoi(node.Value)
+	if err != nil || version < initialVersion {
+		return 0, fmt.Errorf("line %d: invalid version %s", node.Line, node.Value)
+	}
+	if version > CurrentVersion {
+		return 0, fmt.Errorf("Rostifile uses format version %d but this rostictl supports only versions up to %d, please upgrade rostictl", version, CurrentVersion)
+	}
+
+	return version, nil
+}
+
+// applyMigrations runs migrations newer than the version on the node and
+// returns their descriptions
+func applyMigrations(node *yaml3.Node, version int) ([]string, error) {
+	applied := []string{}
+	for _, m := range migrations {
+		if m.version <= version {
+			continue
+		}
+
+		err := m.apply(node)
+		if err != nil {
+			return applied, fmt.Errorf("migration to version %d error: %w", m.version, err)
+		}
+		applied = append(applied, m.description)
+	}
+
+	return applied, nil
+}
+
+// Migrate upgrades Rostifile content to the current version. It returns the
+// new content, the original version and descriptions of applied migrations.
+// The content is returned untouched when it's up to date.
+func Migrate(body []byte) ([]byte, int, []string, error) {
+	document, root, err := decodeDocument(body)
+	if err != nil {
+		return nil, 0, nil, errors.Wrap(err, "Rostifile parsing error")
+	}
+	if root == nil {
+		return body, CurrentVersion, nil, nil
+	}
+
+	version, err := documentVersion(root)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	if version == CurrentVersion {
+		return body, version, nil, nil
+	}
+
+	applied, err := applyMigrations(root, version)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	// Environments in Rostifile are in the same format
+	environments := mappingValue(root, "environments")
+	if environments != nil && environments.Kind == yaml3.MappingNode {
+		for i := 1; i < len(environments.Content); i += 2 {
+			if environments.Content[i].Kind == yaml3.MappingNode {
+				_, err = applyMigrations(environments.Content[i], version)
+				if err != nil {
+					return nil, 0, nil, err
+				}
+			}
+		}
+	}
+
+	// Version goes first
+	versionNode := mappingValue(root, "version")
+	if versionNode == nil {
+		versionNode = &yaml3.Node{Kind: yaml3.ScalarNode, Tag: "!!int"}
+		keyNode := &yaml3.Node{Kind: yaml3.ScalarNode, Tag: "!!str", Value: "version"}
+		root.Content = append([]*yaml3.Node{keyNode, versionNode}, root.Content...)
+	}
+	versionNode.Value = strconv.Itoa(CurrentVersion)
+
+	body, err = encodeDocument(document)
+	return body, version, applied, err
+}
+
+// migrateOverlay upgrades environment's overlay file written for Rostifile
+// of the given version
+func migrateOverlay(body []byte, version int) ([]byte, error) {
+	if version == CurrentVersion {
+		return body, nil
+	}
+
+	document, root, err := decodeDocument(body)
+	if err != nil || root == nil {
+		return body, err
+	}
+
+	_, err = applyMigrations(root, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeDocument(document)
+}
+
+// MigrateFiles upgrades Rostifile and overlay files of its environments on
+// the disk. It returns descriptions of applied migrations.
+func MigrateFiles() ([]string, error) {
+	body, err := ioutil.ReadFile(rostiFilePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Rostifile reading error")
+	}
+
+	migrated, version, applied, err := Migrate(body)
+	if err != nil {
+		return nil, err
+	}
+	if len(applied) == 0 {
+		return applied, nil
+	}
+
+	// Overlays are migrated first so a failure doesn't leave them behind the Rostifile
+	overlayPaths, err := filepath.Glob(rostiFilePath + ".*")
+	if err != nil {
+		return nil, err
+	}
+	for _, overlayPath := range overlayPaths {
+		name := strings.TrimPrefix(filepath.Base(overlayPath), filepath.Base(rostiFilePath)+".")
+		if !environmentNameRegexp.MatchString(name) {
+			continue
+		}
+
+		overlayBody, err := ioutil.ReadFile(overlayPath)
+		if err != nil {
+			return nil, errors.Wrap(err, overlayPath+" reading error")
+		}
+		overlayBody, err = migrateOverlay(overlayBody, version)
+		if err != nil {
+			return nil, errors.Wrap(err, overlayPath+" migration error")
+		}
+		err = ioutil.WriteFile(overlayPath, overlayBody, 0644)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = ioutil.WriteFile(rostiFilePath, migrated, 0644)
+	return applied, err
+}
@@ -0,0 +1,34 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrate(t *testing.T) {
+	body := []byte("# Application\nname: test\n# Jobs\ncrontabs:\n  - \"@daily date\" # backup\nbefore_commands:\n  - echo ${HOME} $${X}\nsecrets:\n  A: ${B}\nenvironments:\n  staging:\n    env:\n      DIR: ${HOME}/app\n")
+
+	migrated, version, applied, err := Migrate(body)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, version)
+	assert.Equal(t, []string{"format version added", "${ escaped as $${ because variables are expanded since version 2"}, applied)
+	assert.Equal(t, "version: 2\n# Application\nname: test\n# Jobs\ncrontabs:\n  - \"@daily date\" # backup\nbefore_commands:\n  - echo $${HOME} $$${X}\nsecrets:\n  A: ${B}\nenvironments:\n  staging:\n    env:\n      DIR: $${HOME}/app\n", string(migrated))
+
+	// Up to date content is not touched
+	again, version, applied, err := Migrate(migrated)
+	assert.Nil(t, err)
+	assert.Equal(t, CurrentVersion, version)
+	assert.Empty(t, applied)
+	assert.Equal(t, migrated, again)
+
+	// Version 1 is only escaped
+	migrated, version, applied, err = Migrate([]byte("version: 1\nname: test\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, version)
+	assert.Len(t, applied, 1)
+	assert.Equal(t, "version: 2\nname: test\n", string(migrated))
+
+	_, _, _, err = Migrate([]byte("version: 99\nname: test\n"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,171 @@
+package parser
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+
+	"github.com/pkg/errors"
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+/*
+Rostifile is written by merging the new content into YAML nodes of the
+existing file. Comments, order of keys and formatting of values that didn't
+change are kept.
+*/
+
+// writeNodes writes the value into the YAML file and keeps comments and
+// order of keys of the existing content. When keys are given, the value is
+// written under them and the rest of the file is left untouched.
+func writeNodes(path string, value interface{}, keys ...string) error {
+	newNode := &yaml3.Node{}
+	err := newNode.Encode(value)
+	if err != nil {
+		return errors.Wrap(err, "Rostifile encoding error")
+	}
+
+	document := &yaml3.Node{Kind: yaml3.DocumentNode, Content: []*yaml3.Node{{Kind: yaml3.MappingNode, Tag: "!!map"}}}
+	body, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, path+" reading error")
+	}
+	if err == nil {
+		// Older versions have to be migrated first so renamed keys are matched
+		body, _, _, err = Migrate(body)
+		if err != nil {
+			return err
+		}
+
+		oldDocument, root, err := decodeDocument(body)
+		if err != nil {
+			return errors.Wrap(err, path+" parsing error")
+		}
+		if root != nil {
+			document = oldDocument
+		}
+	}
+
+	// Find the node the value belongs to, missing ones are created
+	target := document.Content[0]
+	for _, key := range keys {
+		child := mappingValue(target, key)
+		if child == nil || child.Kind != yaml3.MappingNode {
+			mapping := &yaml3.Node{Kind: yaml3.MappingNode, Tag: "!!map"}
+			if child != nil {
+				mergeNode(child, mapping, nil)
+			} else {
+				target.Content = append(target.Content, &yaml3.Node{Kind: yaml3.ScalarNode, Tag: "!!str", Value: key}, mapping)
+			}
+			child = mappingValue(target, key)
+		}
+		target = child
+	}
+
+	previous, err := reencodeNode(target, value)
+	if err != nil {
+		return errors.Wrap(err, path+" parsing error")
+	}
+	mergeNode(target, newNode, previous)
+
+	body, err = encodeDocument(document)
+	if err != nil {
+		return errors.Wrap(err, "Rostifile encoding error")
+	}
+
+	return ioutil.WriteFile(path, body, 0644)
+}
+
+// reencodeNode decodes the node into a new value of the same type as value
+// and encodes it back. The result is what the existing content looks like
+// when it's written, keys with zero values are missing in it for example.
+func reencodeNode(node *yaml3.Node, value interface{}) (*yaml3.Node, error) {
+	decoded := reflect.New(reflect.TypeOf(value))
+	err := node.Decode(decoded.Interface())
+	// Values that don't fit are left empty, the rest is still useful
+	var typeErr *yaml3.TypeError
+	if err != nil && !errors.As(err, &typeErr) {
+		return nil, err
+	}
+
+	previous := &yaml3.Node{}
+	err = previous.Encode(decoded.Elem().Interface())
+	return previous, err
+}
+
+// mergeNode changes the old node so it has the same content as the new one.
+// Comments of the old node and its children are kept. Previous is the old
+// node encoded the same way as the new one, it can be nil.
+func mergeNode(old, new, previous *yaml3.Node) {
+	switch {
+	case old.Kind == yaml3.MappingNode && new.Kind == yaml3.MappingNode:
+		mergeMapping(old, new, previous)
+	case old.Kind == yaml3.SequenceNode && new.Kind == yaml3.SequenceNode:
+		for i, item := range new.Content {
+			if i < len(old.Content) {
+				var previousItem *yaml3.Node
+				if previous != nil && previous.Kind == yaml3.SequenceNode && i < len(previous.Content) {
+					previousItem = previous.Content[i]
+				}
+				mergeNode(old.Content[i], item, previousItem)
+			} else {
+				old.Content = append(old.Content, item)
+			}
+		}
+		if len(old.Content) > len(new.Content) {
+			old.Content = old.Content[:len(new.Content)]
+		}
+	case old.Kind == yaml3.ScalarNode && new.Kind == yaml3.ScalarNode:
+		// Unchanged values keep their original style
+		if old.Value != new.Value || old.ShortTag() != new.ShortTag() {
+			old.Value = new.Value
+			old.Tag = new.Tag
+			old.Style = new.Style
+		}
+	default:
+		headComment, lineComment, footComment := old.HeadComment, old.LineComment, old.FootComment
+		*old = *new
+		old.HeadComment, old.LineComment, old.FootComment = headComment, lineComment, footComment
+	}
+}
+
+// mergeMapping merges keys of the new mapping into the old one. Keys missing
+// in the new mapping are removed, new keys are appended at the end. Keys that
+// are missing in the previous mapping too are kept, they were written with
+// a zero value that is omitted when encoded.
+func mergeMapping(old, new, previous *yaml3.Node) {
+	newValues := make(map[string]*yaml3.Node)
+	var newKeys []*yaml3.Node
+	for i := 0; i+1 < len(new.Content); i += 2 {
+		newValues[new.Content[i].Value] = new.Content[i+1]
+		newKeys = append(newKeys, new.Content[i])
+	}
+
+	content := []*yaml3.Node{}
+	seen := make(map[string]bool)
+	for i := 0; i+1 < len(old.Content); i += 2 {
+		key := old.Content[i]
+		if seen[key.Value] {
+			continue
+		}
+		value, ok := newValues[key.Value]
+		if !ok {
+			if previous != nil && mappingValue(previous, key.Value) == nil {
+				content = append(content, key, old.Content[i+1])
+				seen[key.Value] = true
+			}
+			continue
+		}
+		mergeNode(old.Content[i+1], value, mappingValue(previous, key.Value))
+		content = append(content, key, old.Content[i+1])
+		seen[key.Value] = true
+	}
+
+	for _, key := range newKeys {
+		if !seen[key.Value] {
+			content = append(content, key, newValues[key.Value])
+		}
+	}
+
+	old.Content = content
+}
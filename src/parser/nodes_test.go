@@ -0,0 +1,58 @@
+package parser
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteNodes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rostifile")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "Rostifile")
+	original := "version: 2\n# Name of the app\nname: test # don't change\nplan: 'start'\nenv:\n  # Debug mode\n  DEBUG: \"1\"\n  OLD: x\nhttps: true\nsource_path: .\n"
+	assert.Nil(t, ioutil.WriteFile(path, []byte(original), 0644))
+
+	rostifile := Rostifile{
+		Version:    2,
+		Name:       "test",
+		Plan:       "start",
+		HTTPS:      true,
+		SourcePath: ".",
+		Env:        map[string]string{"DEBUG": "0", "NEW": "y"},
+		Domains:    []string{"test.rostiapp.cz"},
+	}
+	assert.Nil(t, writeNodes(path, rostifile))
+
+	body, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "version: 2\n# Name of the app\nname: test # don't change\nplan: 'start'\nenv:\n  # Debug mode\n  DEBUG: \"0\"\n  NEW: \"y\"\nhttps: true\nsource_path: .\ndomains:\n  - test.rostiapp.cz\n", string(body))
+}
+
+func TestWriteNodesZeroValues(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rostifile")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "Rostifile")
+	original := "version: 2\nname: test\nhttps: false # keep plain http until DNS moves\nfiles:\n  /srv/app/a.txt: hello\nenv:\n  A: \"1\"\n  B: \"2\"\n"
+	assert.Nil(t, ioutil.WriteFile(path, []byte(original), 0644))
+
+	// Keys written with zero values stay, removed values are removed
+	rostifile := Rostifile{
+		Version: 2,
+		Name:    "test",
+		Files:   map[string]File{"/srv/app/a.txt": {Content: "hello"}},
+		Env:     map[string]string{"A": "1"},
+	}
+	assert.Nil(t, writeNodes(path, rostifile))
+
+	body, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "version: 2\nname: test\nhttps: false # keep plain http until DNS moves\nfiles:\n  /srv/app/a.txt: hello\nenv:\n  A: \"1\"\n", string(body))
+}
@@ -0,0 +1,117 @@
+package parser
+
+//go:generate go run gendocs.go
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Types that can be written as a string too, see their UnmarshalYAML methods
+var stringOrObjectTypes = map[string]bool{
+	"Command": true,
+	"CronJob": true,
+	"File":    true,
+}
+
+// Additional constraints of fields that can't be derived from Go types
+var fieldConstraints = map[string]map[string]interface{}{
+	"Rostifile.Name":       {"pattern": nameRegexp.String()},
+	"Rostifile.Technology": {"examples": Technologies},
+	"Rostifile.Plan":       {"enum": Plans},
+	"Rostifile.Env":        {"propertyNames": map[string]interface{}{"pattern": envNameRegexp.String()}},
+	"Process.Name":         {"pattern": processNameRegexp.String()},
+	"Process.Env":          {"propertyNames": map[string]interface{}{"pattern": envNameRegexp.String()}},
+	"Process.StopSignal":   {"enum": stopSignals},
+	"Process.AutoRestart":  {"enum": restartPolicies},
+	"Process.LogMaxBytes":  {"pattern": logSizeRegexp.String()},
+	"Command.Env":          {"propertyNames": map[string]interface{}{"pattern": envNameRegexp.String()}},
+	"CronJob.Name":         {"pattern": cronNameRegexp.String()},
+}
+
+// Schema returns JSON schema of Rostifile. Descriptions of the fields are
+// taken from comments in types.go.
+func Schema() map[string]interface{} {
+	definitions := make(map[string]interface{})
+
+	schema := objectSchema(reflect.TypeOf(Rostifile{}), definitions)
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = "Rostifile"
+	schema["definitions"] = definitions
+
+	return schema
+}
+
+// objectSchema returns schema of the struct, schemas of nested structs are
+// added into definitions
+func objectSchema(t reflect.Type, definitions map[string]interface{}) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := []string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("yaml"), ",")
+		if tag[0] == "" || tag[0] == "-" {
+			continue
+		}
+
+		property := typeSchema(field.Type, definitions)
+		if doc, ok := fieldDocs[t.Name()+"."+field.Name]; ok {
+			property["description"] = doc
+		}
+		for key, value := range fieldConstraints[t.Name()+"."+field.Name] {
+			property[key] = value
+		}
+		properties[tag[0]] = property
+
+		// Fields without omitempty are required, except booleans that are false by default
+		if len(tag) == 1 && field.Type.Kind() != reflect.Bool {
+			required = append(required, tag[0])
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+// typeSchema returns schema of a Go type
+func typeSchema(t reflect.Type, definitions map[string]interface{}) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeSchema(t.Elem(), definitions)
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), definitions)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem(), definitions)}
+	case reflect.Struct:
+		if _, ok := definitions[t.Name()]; !ok {
+			// Placeholder stops recursion of self-referencing types
+			definitions[t.Name()] = nil
+			definition := objectSchema(t, definitions)
+			if stringOrObjectTypes[t.Name()] {
+				definition = map[string]interface{}{
+					"oneOf": []interface{}{map[string]interface{}{"type": "string"}, definition},
+				}
+			}
+			definitions[t.Name()] = definition
+		}
+		return map[string]interface{}{"$ref": "#/definitions/" + t.Name()}
+	}
+
+	// Interfaces and anything else can hold any value
+	return map[string]interface{}{}
+}
@@ -0,0 +1,48 @@
+package parser
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rosti-cz/cli/src/parser/docgen"
+	"github.com/stretchr/testify/assert"
+)
+
+// Descriptions in the schema have to match comments in types.go, run
+// go generate when this test fails.
+func TestFieldDocs(t *testing.T) {
+	docs, err := docgen.Extract("types.go")
+	assert.Nil(t, err)
+	assert.Equal(t, docs, fieldDocs)
+}
+
+func TestSchema(t *testing.T) {
+	body, err := json.Marshal(Schema())
+	assert.Nil(t, err)
+
+	schema := struct {
+		Properties  map[string]map[string]interface{} `json:"properties"`
+		Required    []string                          `json:"required"`
+		Definitions map[string]map[string]interface{} `json:"definitions"`
+	}{}
+	assert.Nil(t, json.Unmarshal(body, &schema))
+
+	// Every YAML field of Rostifile is in the schema
+	rostifileType := reflect.TypeOf(Rostifile{})
+	for i := 0; i < rostifileType.NumField(); i++ {
+		name := strings.Split(rostifileType.Field(i).Tag.Get("yaml"), ",")[0]
+		assert.Contains(t, schema.Properties, name)
+	}
+	assert.Equal(t, []string{"name"}, schema.Required)
+	assert.Equal(t, fieldDocs["Rostifile.Name"], schema.Properties["name"]["description"])
+	assert.Equal(t, []interface{}{"python", "node", "php", "ruby", "binary"}, schema.Properties["technology"]["examples"])
+	assert.Equal(t, "#/definitions/Process", schema.Properties["processes"]["items"].(map[string]interface{})["$ref"])
+
+	for _, name := range []string{"Process", "Command", "CronJob", "Healthcheck", "File"} {
+		assert.Contains(t, schema.Definitions, name)
+	}
+	assert.Contains(t, schema.Definitions["Command"], "oneOf")
+	assert.Equal(t, "object", schema.Definitions["Process"]["type"])
+}
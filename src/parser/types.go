@@ -2,59 +2,413 @@ package parser
 
 import (
 	"errors"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"path"
+	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/robfig/cron/v3"
+	"github.com/rosti-cz/cli/src/interpolate"
+	"github.com/rosti-cz/cli/src/secrets"
 )
 
 // Process tells the code what to run in background
 type Process struct {
-	Name            string `yaml:"name"`
-	Command         string `yaml:"command"`
-	StopKillAsGroup bool   `yaml:"stop_kill_as_group,omitempty"`
+	// Name of the process, it can contain only a-zA-Z0-9_
+	Name string `yaml:"name"`
+	// Command running the process, it has to stay in foreground
+	Command string `yaml:"command"`
+	// Send the stop signal to the whole process group
+	StopKillAsGroup bool `yaml:"stop_kill_as_group,omitempty"`
+	// Environment variables of the process, they override the global ones
+	Env map[string]string `yaml:"env,omitempty"`
+	// Number of instances of the process. Default is 1.
+	Numprocs int `yaml:"numprocs,omitempty"`
+	// Working directory of the process. Default is /srv/app.
+	Directory string `yaml:"directory,omitempty"`
+	// Order of starting and stopping, lower priority starts first and stops last. Default is 999.
+	Priority int `yaml:"priority,omitempty"`
+	// How many seconds the process has to stay up to be considered as started. Default is 1.
+	StartSecs *int `yaml:"startsecs,omitempty"`
+	// How many seconds to wait for the process to stop before it's killed. Default is 10.
+	StopWaitSecs int `yaml:"stopwaitsecs,omitempty"`
+	// Signal used to stop the process, possible values are: TERM,HUP,INT,QUIT,KILL,USR1,USR2. Default is TERM.
+	StopSignal string `yaml:"stopsignal,omitempty"`
+	// Restart policy, possible values are: true,false,unexpected. Default is true.
+	AutoRestart string `yaml:"autorestart,omitempty"`
+	// Maximum size of the log file before it's rotated, for example 2MB. Default is 2MB.
+	LogMaxBytes string `yaml:"log_max_bytes,omitempty"`
+	// Number of rotated log files to keep. Default is 5.
+	LogBackups *int `yaml:"log_backups,omitempty"`
+}
+
+// Command is a shell command run in the container during deploy. It can be
+// written as a string or as a structure with more options.
+type Command struct {
+	// Shell script, it can have multiple lines
+	Script string `yaml:"script"`
+	// Directory where the script runs
+	Workdir string `yaml:"workdir,omitempty"`
+	// Additional environment variables of the script
+	Env map[string]string `yaml:"env,omitempty"`
+	// Timeout in seconds, zero means no timeout
+	Timeout int `yaml:"timeout,omitempty"`
+	// Continue the deploy even if the script fails
+	IgnoreErrors bool `yaml:"ignore_errors,omitempty"`
+}
+
+// NewCommands returns list of commands with given scripts
+func NewCommands(scripts ...string) []Command {
+	commands := make([]Command, 0, len(scripts))
+	for _, script := range scripts {
+		commands = append(commands, Command{Script: script})
+	}
+
+	return commands
+}
+
+// UnmarshalYAML allows to use a string with the script instead of the whole structure
+func (c *Command) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var script string
+	err := unmarshal(&script)
+	if err == nil {
+		c.Script = script
+		return nil
+	}
+
+	type plain Command
+	return unmarshal((*plain)(c))
+}
+
+// MarshalYAML encodes the command as a simple string when only the script is set
+func (c Command) MarshalYAML() (interface{}, error) {
+	if c.Workdir == "" && len(c.Env) == 0 && c.Timeout == 0 && !c.IgnoreErrors {
+		return c.Script, nil
+	}
+
+	type plain Command
+	return plain(c), nil
+}
+
+// String returns the first line of the script
+func (c Command) String() string {
+	script := strings.TrimSpace(c.Script)
+	if i := strings.Index(script, "\n"); i >= 0 {
+		return script[:i] + " ..."
+	}
+
+	return script
+}
+
+// CronJob is a job run periodically by cron. It can be written as a string
+// in the standard crontab format or as a structure.
+type CronJob struct {
+	// Name of the job, used in the name of its log file
+	Name string `yaml:"name,omitempty"`
+	// Schedule in the standard cron format, for example "*/15 * * * *" or "@daily"
+	Schedule string `yaml:"schedule,omitempty"`
+	// Shell command
+	Command string `yaml:"command,omitempty"`
+	// Timeout in seconds, zero means no timeout
+	Timeout int `yaml:"timeout,omitempty"`
+	// Path of the log file. Default is /srv/log/cron-<name>.log.
+	Log string `yaml:"log,omitempty"`
+
+	// Raw is the original line when the job is written as a string
+	Raw string `yaml:"-"`
+}
+
+var cronVariableRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*\s*=`)
+
+// UnmarshalYAML allows to use a crontab line instead of the whole structure
+func (j *CronJob) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var line string
+	err := unmarshal(&line)
+	if err == nil {
+		j.Raw = line
+
+		fields := strings.Fields(line)
+		scheduleFields := 5
+		if strings.HasPrefix(line, "@") {
+			scheduleFields = 1
+		}
+		if len(fields) > scheduleFields && !j.IsVariable() {
+			j.Schedule = strings.Join(fields[:scheduleFields], " ")
+			j.Command = strings.Join(fields[scheduleFields:], " ")
+		}
+
+		return nil
+	}
+
+	type plain CronJob
+	return unmarshal((*plain)(j))
+}
+
+// MarshalYAML keeps jobs written as a string in their original form
+func (j CronJob) MarshalYAML() (interface{}, error) {
+	if j.Raw != "" {
+		return j.Raw, nil
+	}
+
+	type plain CronJob
+	return plain(j), nil
+}
+
+// IsVariable returns true for crontab lines setting a variable, like MAILTO=...
+func (j *CronJob) IsVariable() bool {
+	return j.Raw != "" && cronVariableRegexp.MatchString(strings.TrimSpace(j.Raw))
+}
+
+// LogPath returns path of the job's log file
+func (j *CronJob) LogPath() string {
+	if j.Log != "" {
+		return j.Log
+	}
+	return "/srv/log/cron-" + j.Name + ".log"
+}
+
+// Healthcheck describes how to check the application works after deploy
+type Healthcheck struct {
+	// Path requested on every domain of the application. Default is /.
+	Path string `yaml:"path,omitempty"`
+	// Expected HTTP status code. Default is 200.
+	Status int `yaml:"status,omitempty"`
+	// Text that has to be included in the response body
+	BodyContains string `yaml:"body_contains,omitempty"`
+	// Timeout of a single request in seconds. Default is 10.
+	Timeout int `yaml:"timeout,omitempty"`
+	// Number of attempts before the deploy is considered as failed. Default is 12.
+	Retries int `yaml:"retries,omitempty"`
+	// Pause between two attempts in seconds. Default is 5.
+	Interval int `yaml:"interval,omitempty"`
+	// Restore the previous code when the check fails
+	Rollback bool `yaml:"rollback,omitempty"`
+}
+
+// SetDefaults sets default values of the unset fields
+func (h *Healthcheck) SetDefaults() {
+	if h.Path == "" {
+		h.Path = "/"
+	}
+	if h.Status == 0 {
+		h.Status = 200
+	}
+	if h.Timeout == 0 {
+		h.Timeout = 10
+	}
+	if h.Retries == 0 {
+		h.Retries = 12
+	}
+	if h.Interval == 0 {
+		h.Interval = 5
+	}
+}
+
+// File is a file written into the container during deploy. It can be
+// written as a string with the content or as a structure.
+type File struct {
+	// Content of the file
+	Content string `yaml:"content,omitempty"`
+	// Local file which content is uploaded instead of content
+	Source string `yaml:"source,omitempty"`
+	// Permissions of the file in octal notation. Default is 0644.
+	Mode string `yaml:"mode,omitempty"`
+
+	// Variables are expanded in content of the source file
+	template bool
+}
+
+// UnmarshalYAML allows to use the string with content instead of the whole structure
+func (f *File) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var content string
+	err := unmarshal(&content)
+	if err == nil {
+		f.Content = content
+		return nil
+	}
+
+	type plain File
+	return unmarshal((*plain)(f))
+}
+
+// MarshalYAML encodes the file as a simple string when only the content is set
+func (f File) MarshalYAML() (interface{}, error) {
+	if f.Source == "" && f.Mode == "" {
+		return f.Content, nil
+	}
+
+	type plain File
+	return plain(f), nil
+}
+
+// FileMode returns permissions of the file
+func (f *File) FileMode() (os.FileMode, error) {
+	if f.Mode == "" {
+		return 0644, nil
+	}
+
+	mode, err := strconv.ParseUint(f.Mode, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid file mode %s", f.Mode)
+	}
+
+	return os.FileMode(mode), nil
+}
+
+// Load returns content of the file. The content is read from the local
+// file when source is set and variables in it are expanded the same way as
+// in Rostifile when the Rostifile's format supports them.
+func (f *File) Load() (string, error) {
+	content, missing, err := f.load()
+	if err != nil {
+		return "", err
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("variables used in %s are not set: %s", f.Source, strings.Join(missing, ", "))
+	}
+
+	return content, nil
+}
+
+// load returns content of the file and names of variables used in the
+// source file that are not set
+func (f *File) load() (string, []string, error) {
+	if f.Source == "" {
+		return f.Content, nil, nil
+	}
+
+	body, err := ioutil.ReadFile(f.Source)
+	if err != nil {
+		return "", nil, fmt.Errorf("reading file %s error: %w", f.Source, err)
+	}
+
+	if !f.template {
+		return string(body), nil, nil
+	}
+
+	content, missing := interpolate.Expand(string(body), lookup)
+	return content, missing, nil
 }
 
 // Rostifile is structure that keeps info about desired application.
 type Rostifile struct {
+	// Version of Rostifile format
+	Version int `yaml:"version,omitempty"`
+	// Unique name of the application, it can contain only a-zA-Z0-9._
 	Name string `yaml:"name"`
 	// Runtime image of the application, default is defined in the backend, usually the latest.
 	Runtime string `yaml:"runtime,omitempty"`
 	// Primary technology configured in application's container
-	Technology        string `yaml:"technology,omitempty"`
+	Technology string `yaml:"technology,omitempty"`
+	// Version of the primary technology
 	TechnologyVersion string `yaml:"technology_version,omitempty"`
 	// List of domains configured on the load balancer for this application
 	Domains []string `yaml:"domains,omitempty"`
 	// Enable/Disable HTTPS for all domains
-	HTTPS bool `yaml:"https"`
+	HTTPS bool `yaml:"https,omitempty"`
 	// Directory with the source code that will be uploaded onto server into /srv/app. Default is .
 	SourcePath string `yaml:"source_path,omitempty"`
+	// Commands to run locally in source_path before the code is archived and uploaded
+	BuildCommands []string `yaml:"build_commands,omitempty"`
+	// Directory inside source_path that is uploaded instead of the whole source_path, usually output of build_commands
+	BuildOutput string `yaml:"build_output,omitempty"`
 	// Plan of the service, possible values are: static,start,start+,normal,normal+,pro,pro+,business,business+. Default is defined in the backend.
 	Plan string `yaml:"plan,omitempty"`
 	// Application port that can be changed only during creation of the application. Default is 8080. No effect for PHP apps.
 	AppPort int `yaml:"app_port,omitempty"`
+	// Environment variables of all processes and deploy commands, also written into /srv/app/.env
+	Env map[string]string `yaml:"env,omitempty"`
+	// Encrypted environment variables managed by secrets command, they are decrypted during deploy
+	Secrets map[string]string `yaml:"secrets,omitempty" interpolate:"-"`
 	// List of background processes running in supervisor
 	Processes []Process `yaml:"processes,omitempty"`
-	// Crontab jobs
-	Crontabs []string `yaml:"crontabs,omitempty"`
+	// Crontab jobs, either lines in the standard crontab format or structures with name, schedule, command, timeout and log
+	Crontabs []CronJob `yaml:"crontabs,omitempty"`
 	// Commands to run before deploy begins.
-	BeforeCommands []string `yaml:"before_commands,omitempty"`
+	BeforeCommands []Command `yaml:"before_commands,omitempty"`
 	// Commands to run after deploy ends.
-	AfterCommands []string `yaml:"after_commands,omitempty"`
+	AfterCommands []Command `yaml:"after_commands,omitempty"`
 	// Commmands to runs when the application is created
-	InitialCommands []string `yaml:"initial_commands,omitempty"`
+	InitialCommands []Command `yaml:"initial_commands,omitempty"`
 	// What directories and files to exclude from the deploy
 	Exclude []string `yaml:"exclude,omitempty"`
+	// Check of the application done after deploy, the deploy fails when the application is not healthy
+	Healthcheck *Healthcheck `yaml:"healthcheck,omitempty"`
 	// Map of files where key is path to the file (including /srv) and value is content of the file
-	Files map[string]string `yaml:"files,omitempty"`
+	// or structure with content, source and mode fields.
+	Files map[string]File `yaml:"files,omitempty"`
+	// Overlays merged over the Rostifile when an environment is selected with --env,
+	// key is name of the environment
+	Environments map[string]interface{} `yaml:"environments,omitempty"`
+}
+
+// SetDefaults sets default values of fields that are not set
+func (r *Rostifile) SetDefaults() {
+	if r.SourcePath == "" {
+		r.SourcePath = "."
+	}
+}
+
+// CronJobs returns cron jobs defined in Rostifile without lines setting
+// variables. Jobs without a name get name job<N> based on their position.
+func (r *Rostifile) CronJobs() []CronJob {
+	jobs := []CronJob{}
+	for i, job := range r.Crontabs {
+		if job.IsVariable() {
+			continue
+		}
+		if job.Name == "" {
+			job.Name = "job" + strconv.Itoa(i+1)
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs
+}
+
+// ArchivePath returns path of the directory that is uploaded into the container
+func (r *Rostifile) ArchivePath() string {
+	if r.BuildOutput != "" {
+		return filepath.Join(r.SourcePath, r.BuildOutput)
+	}
+
+	return r.SourcePath
+}
+
+// ProcessEnv returns environment variables of the process merged with the global ones
+func (r *Rostifile) ProcessEnv(process Process) map[string]string {
+	env := make(map[string]string, len(r.Env)+len(process.Env))
+	for key, value := range r.Env {
+		env[key] = value
+	}
+	for key, value := range process.Env {
+		env[key] = value
+	}
+
+	return env
+}
+
+// FilePaths returns sorted list of paths of files defined in Rostifile
+func (r *Rostifile) FilePaths() []string {
+	paths := make([]string, 0, len(r.Files))
+	for filePath := range r.Files {
+		paths = append(paths, filePath)
+	}
+	sort.Strings(paths)
+
+	return paths
 }
 
 // Validate runs static validation over the structure and sets defaults values when possible.
 func (r *Rostifile) Validate() []error {
 	errs := []error{}
 
-	// Set up default source
-	if r.SourcePath == "" {
-		r.SourcePath = "."
-	}
+	r.SetDefaults()
 
 	info, err := os.Stat(r.SourcePath)
 	if os.IsNotExist(err) {
@@ -63,45 +417,235 @@ func (r *Rostifile) Validate() []error {
 		errs = append(errs, errors.New("\""+r.SourcePath+"\" in source_path is not a directory"))
 	}
 
-	// Name validation, the most important one
-	re, err := regexp.Compile("^[a-zA-Z0-9_\\.]*$")
-	if err != nil {
-		errs = append(errs, err)
+	// Build output has to be located inside source_path
+	if r.BuildOutput != "" {
+		cleaned := filepath.Clean(r.BuildOutput)
+		if filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+			errs = append(errs, errors.New("build_output has to be a directory inside source_path"))
+		}
 	}
 
-	if !re.MatchString(r.Name) {
+	// Name validation, the most important one
+	if !nameRegexp.MatchString(r.Name) {
 		errs = append(errs, errors.New("name can contain only these characters: a-zA-Z0-9._"))
 	}
 
 	// Processes validation
-	re, err = regexp.Compile("^[a-zA-Z0-9_]*$")
-	if err != nil {
-		errs = append(errs, err)
-	}
-
 	for _, process := range r.Processes {
-		if !re.MatchString(process.Name) {
+		if !processNameRegexp.MatchString(process.Name) {
 			errs = append(errs, errors.New("name can contain only these characters: a-zA-Z0-9_"))
 		}
+		errs = append(errs, process.validate()...)
+	}
+
+	// Environment variables validation
+	errs = append(errs, ValidateEnv(r.Env)...)
+
+	// Cron jobs validation
+	cronNames := make(map[string]bool)
+	for _, job := range r.CronJobs() {
+		if !cronNameRegexp.MatchString(job.Name) {
+			errs = append(errs, errors.New("name of cron job "+job.Name+" can contain only these characters: a-zA-Z0-9_-"))
+		}
+		if cronNames[job.Name] {
+			errs = append(errs, errors.New("cron job "+job.Name+" is defined more than once"))
+		}
+		cronNames[job.Name] = true
+
+		err := validateSchedule(job.Schedule)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cron job %s has invalid schedule \"%s\": %w", job.Name, job.Schedule, err))
+		}
+		if strings.TrimSpace(job.Command) == "" {
+			errs = append(errs, errors.New("cron job "+job.Name+" has no command"))
+		}
+		if strings.ContainsAny(job.Command+job.Log, "\r\n") {
+			errs = append(errs, errors.New("cron job "+job.Name+" can't contain new lines"))
+		}
+		if job.Timeout < 0 {
+			errs = append(errs, errors.New("timeout of cron job "+job.Name+" can't be negative"))
+		}
+	}
+
+	// Commands validation
+	for _, commands := range [][]Command{r.BeforeCommands, r.AfterCommands, r.InitialCommands} {
+		for _, command := range commands {
+			if strings.TrimSpace(command.Script) == "" {
+				errs = append(errs, errors.New("command can't have an empty script"))
+			}
+			if command.Timeout < 0 {
+				errs = append(errs, errors.New("timeout of command \""+command.String()+"\" can't be negative"))
+			}
+			errs = append(errs, ValidateEnv(command.Env)...)
+		}
 	}
 
-	// Technology validation
-	validTechs := []string{
-		"python",
-		"node",
-		"php",
-		"ruby",
-		"",
+	// Secrets validation, they are not decrypted here so only names and format are checked
+	for name, value := range r.Secrets {
+		if !envNameRegexp.MatchString(name) {
+			errs = append(errs, errors.New("secret "+name+" can contain only these characters: a-zA-Z0-9_ and it can't start with a number"))
+		}
+		if !secrets.IsEncrypted(value) {
+			errs = append(errs, errors.New("secret "+name+" is not encrypted, use secrets command to set it"))
+		}
 	}
-	var found bool
-	for _, validTech := range validTechs {
-		if validTech == r.Technology {
-			found = true
-			break
+
+	// Files validation
+	for _, filePath := range r.FilePaths() {
+		file := r.Files[filePath]
+
+		if !strings.HasPrefix(path.Clean(filePath), "/srv/") {
+			errs = append(errs, errors.New("file "+filePath+" has to be located in /srv"))
+		}
+
+		if file.Source != "" && file.Content != "" {
+			errs = append(errs, errors.New("file "+filePath+" can have either content or source, not both"))
+		}
+
+		if file.Source != "" {
+			_, err := os.Stat(file.Source)
+			if os.IsNotExist(err) {
+				errs = append(errs, errors.New("source "+file.Source+" of file "+filePath+" doesn't exist"))
+			}
+		}
+
+		_, err := file.FileMode()
+		if err != nil {
+			errs = append(errs, errors.New("file "+filePath+": "+err.Error()))
 		}
 	}
-	if !found {
-		errs = append(errs, errors.New("only valid technologies are python, node, php, ruby and empty string"))
+
+	// Healthcheck validation
+	if r.Healthcheck != nil {
+		if !strings.HasPrefix(r.Healthcheck.Path, "/") && r.Healthcheck.Path != "" {
+			errs = append(errs, errors.New("healthcheck path has to start with /"))
+		}
+		if r.Healthcheck.Status < 0 || r.Healthcheck.Status > 599 {
+			errs = append(errs, errors.New("healthcheck status is not a valid HTTP status code"))
+		}
+		if r.Healthcheck.Timeout < 0 || r.Healthcheck.Retries < 0 || r.Healthcheck.Interval < 0 {
+			errs = append(errs, errors.New("healthcheck timeout, retries and interval can't be negative"))
+		}
+	}
+
+	return errs
+}
+
+// TechnologyBinary is technology of applications that don't need any runtime
+// environment, like compiled Go programs
+const TechnologyBinary = "binary"
+
+// Valid values of Rostifile options, they are shared by the validation and the JSON schema
+var (
+	Technologies      = []string{"python", "node", "php", "ruby", TechnologyBinary}
+	Plans             = []string{"static", "start", "start+", "normal", "normal+", "pro", "pro+", "business", "business+"}
+	nameRegexp        = regexp.MustCompile(`^[a-zA-Z0-9_\.]*$`)
+	processNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_]*$`)
+)
+
+// Valid values of process options
+var (
+	stopSignals     = []string{"TERM", "HUP", "INT", "QUIT", "KILL", "USR1", "USR2"}
+	restartPolicies = []string{"true", "false", "unexpected"}
+	logSizeRegexp   = regexp.MustCompile(`^[0-9]+(KB|MB|GB)?$`)
+)
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks options of the process
+func (p *Process) validate() []error {
+	errs := []error{}
+
+	if strings.ContainsAny(p.Command, "\r\n") {
+		errs = append(errs, errors.New("command of process "+p.Name+" can't contain new lines"))
+	}
+	if p.Numprocs < 0 {
+		errs = append(errs, errors.New("numprocs of process "+p.Name+" can't be negative"))
+	}
+	if p.Directory != "" && (!path.IsAbs(p.Directory) || strings.ContainsAny(p.Directory, "\r\n")) {
+		errs = append(errs, errors.New("directory of process "+p.Name+" has to be an absolute path"))
+	}
+	if p.Priority < 0 {
+		errs = append(errs, errors.New("priority of process "+p.Name+" can't be negative"))
+	}
+	if p.StartSecs != nil && *p.StartSecs < 0 {
+		errs = append(errs, errors.New("startsecs of process "+p.Name+" can't be negative"))
+	}
+	if p.StopWaitSecs < 0 {
+		errs = append(errs, errors.New("stopwaitsecs of process "+p.Name+" can't be negative"))
+	}
+	if p.StopSignal != "" && !contains(stopSignals, p.StopSignal) {
+		errs = append(errs, errors.New("stopsignal of process "+p.Name+" has to be one of "+strings.Join(stopSignals, ", ")))
+	}
+	if p.AutoRestart != "" && !contains(restartPolicies, p.AutoRestart) {
+		errs = append(errs, errors.New("autorestart of process "+p.Name+" has to be one of "+strings.Join(restartPolicies, ", ")))
+	}
+	if p.LogMaxBytes != "" && !logSizeRegexp.MatchString(p.LogMaxBytes) {
+		errs = append(errs, errors.New("log_max_bytes of process "+p.Name+" has to be a number optionally followed by KB, MB or GB"))
+	}
+	if p.LogBackups != nil && *p.LogBackups < 0 {
+		errs = append(errs, errors.New("log_backups of process "+p.Name+" can't be negative"))
+	}
+	errs = append(errs, ValidateEnv(p.Env)...)
+
+	return errs
+}
+
+var cronNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+var cronSundayRegexp = regexp.MustCompile(`\b7\b`)
+
+// validateSchedule checks the schedule against what Vixie cron in the container
+// understands. The robfig parser is used for the fields themselves but it differs
+// in a few details: it doesn't know @reboot and 7 as Sunday and it accepts @every,
+// CRON_TZ and ? which cron doesn't.
+func validateSchedule(schedule string) error {
+	schedule = strings.TrimSpace(schedule)
+
+	if strings.HasPrefix(schedule, "CRON_TZ=") || strings.HasPrefix(schedule, "TZ=") {
+		return errors.New("time zones are not supported")
+	}
+	if strings.HasPrefix(schedule, "@") {
+		switch schedule {
+		case "@reboot", "@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly":
+			return nil
+		}
+		return errors.New("unknown descriptor " + schedule)
+	}
+	if strings.Contains(schedule, "?") {
+		return errors.New("? is not supported, use * instead")
+	}
+
+	fields := strings.Fields(schedule)
+	if len(fields) == 5 {
+		// 7 is Sunday as well as 0, the parser allows only 0-6
+		fields[4] = cronSundayRegexp.ReplaceAllString(fields[4], "6")
+	}
+
+	_, err := cron.ParseStandard(strings.Join(fields, " "))
+	return err
+}
+
+var envNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// ValidateEnv checks names and values of environment variables
+func ValidateEnv(env map[string]string) []error {
+	errs := []error{}
+
+	for key, value := range env {
+		if !envNameRegexp.MatchString(key) {
+			errs = append(errs, errors.New("environment variable "+key+" can contain only these characters: a-zA-Z0-9_ and it can't start with a number"))
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			errs = append(errs, errors.New("value of environment variable "+key+" can't contain new lines"))
+		}
 	}
 
 	return errs
@@ -0,0 +1,127 @@
+package parser
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestValidateCronSchedule(t *testing.T) {
+	cases := map[string]bool{
+		"*/15 * * * *":          true,
+		"0 3 * * 1-5":           true,
+		"0 0 * * 7":             true,
+		"0 0 * * 5-7":           true,
+		"0 0 * * sun":           true,
+		"@daily":                true,
+		"@reboot":               true,
+		"0 0 * * 8":             false,
+		"0 0 * ? *":             false,
+		"@every 5m":             false,
+		"@sometimes":            false,
+		"CRON_TZ=UTC 0 0 * * *": false,
+		"0 0 * *":               false,
+		"0 0 0 * * *":           false,
+		"61 * * * *":            false,
+	}
+
+	for schedule, valid := range cases {
+		rostifile := Rostifile{
+			Name:     "test",
+			Crontabs: []CronJob{{Name: "job", Schedule: schedule, Command: "true"}},
+		}
+
+		scheduleErrors := []error{}
+		for _, err := range rostifile.Validate() {
+			if strings.Contains(err.Error(), "schedule") {
+				scheduleErrors = append(scheduleErrors, err)
+			}
+		}
+
+		if valid {
+			assert.Empty(t, scheduleErrors, schedule)
+		} else {
+			assert.NotEmpty(t, scheduleErrors, schedule)
+		}
+	}
+}
+
+func TestFileUnmarshal(t *testing.T) {
+	rostifile := Rostifile{}
+	body := "files:\n  /srv/app/a.txt: hello\n  /srv/app/b.sh:\n    source: b.sh\n    mode: \"0755\"\n"
+	assert.Nil(t, yaml.Unmarshal([]byte(body), &rostifile))
+
+	assert.Equal(t, File{Content: "hello"}, rostifile.Files["/srv/app/a.txt"])
+	assert.Equal(t, File{Source: "b.sh", Mode: "0755"}, rostifile.Files["/srv/app/b.sh"])
+
+	// Content only files are written back as strings
+	out, err := yaml.Marshal(rostifile.Files)
+	assert.Nil(t, err)
+	assert.Equal(t, "/srv/app/a.txt: hello\n/srv/app/b.sh:\n  source: b.sh\n  mode: \"0755\"\n", string(out))
+}
+
+func TestValidateFiles(t *testing.T) {
+	cases := []struct {
+		path    string
+		file    File
+		message string
+	}{
+		{"/srv/../etc/passwd", File{Content: "x"}, "file /srv/../etc/passwd has to be located in /srv"},
+		{"/srv/app/mode.txt", File{Content: "x", Mode: "999"}, "file /srv/app/mode.txt: invalid file mode 999"},
+		{"/srv/app/mode.txt", File{Content: "x", Mode: "01777"}, "file /srv/app/mode.txt: invalid file mode 01777"},
+		{"/srv/app/both.txt", File{Content: "x", Source: "types.go"}, "file /srv/app/both.txt can have either content or source, not both"},
+	}
+
+	for _, c := range cases {
+		rostifile := Rostifile{
+			Name:  "test",
+			Files: map[string]File{c.path: c.file},
+		}
+		assert.Contains(t, rostifile.Validate(), errors.New(c.message))
+	}
+
+	rostifile := Rostifile{
+		Name:  "test",
+		Files: map[string]File{"/srv/app/ok.sh": {Source: "types.go", Mode: "0755"}},
+	}
+	assert.Empty(t, rostifile.Validate())
+}
+
+func TestFileLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rostictl")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	template := filepath.Join(dir, "app.conf")
+	assert.Nil(t, ioutil.WriteFile(template, []byte("host=${HOST}\nport=${PORT:-8080}\nshell=$${HOME}\n"), 0644))
+
+	SetLookup(func(name string) (string, bool) {
+		if name == "HOST" {
+			return "example.com", true
+		}
+		return "", false
+	})
+	defer SetLookup(os.LookupEnv)
+
+	// Source is a template, inline content is expanded by Parse
+	file := File{Source: template, template: true}
+	content, err := file.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, "host=example.com\nport=8080\nshell=${HOME}\n", content)
+
+	assert.Nil(t, ioutil.WriteFile(template, []byte("user=${USER_NAME}\n"), 0644))
+	_, err = file.Load()
+	assert.EqualError(t, err, "variables used in "+template+" are not set: USER_NAME")
+
+	// Rostifiles older than version 2 don't expand anything
+	file.template = false
+	content, err = file.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, "user=${USER_NAME}\n", content)
+}
@@ -0,0 +1,185 @@
+package parser
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rosti-cz/cli/src/suggest"
+	"gopkg.in/yaml.v2"
+)
+
+// Report holds all problems found in Rostifile
+type Report struct {
+	// Errors make the Rostifile unusable
+	Errors []error
+	// Warnings are likely mistakes that don't stop the deploy
+	Warnings []string
+}
+
+// OK returns true when there are no errors
+func (r *Report) OK() bool {
+	return len(r.Errors) == 0
+}
+
+// Go types in messages of the YAML decoder are not useful for users
+var yamlTypeRegexp = regexp.MustCompile(` in type [a-zA-Z0-9_.\[\]]+`)
+
+// Check parses Rostifile strictly and returns it together with all errors
+// and warnings found in it. Error is returned only when the file can't be
+// read or parsed at all.
+func Check() (*Rostifile, Report, error) {
+	report := Report{}
+
+	rostifile, missing, err := parse(true)
+	if err != nil {
+		return rostifile, report, err
+	}
+	for _, name := range missing {
+		report.Errors = append(report.Errors, fmt.Errorf("variable %s is not set, set it or use ${%s:-default}", name, name))
+	}
+	for _, filePath := range rostifile.FilePaths() {
+		file := rostifile.Files[filePath]
+		// Missing source is reported by Validate
+		_, missing, err := file.load()
+		if err == nil {
+			for _, name := range missing {
+				report.Errors = append(report.Errors, fmt.Errorf("variable %s used in %s is not set, set it or use ${%s:-default}", name, file.Source, name))
+			}
+		}
+	}
+
+	body, err := ioutil.ReadFile(rostiFilePath)
+	if err != nil {
+		return rostifile, report, errors.Wrap(err, "Rostifile reading error")
+	}
+	_, version, _, err := Migrate(body)
+	if err != nil {
+		return rostifile, report, err
+	}
+
+	// Strict decoding finds unknown and duplicated keys, the lenient result
+	// from Parse is used for the rest of the checks. Every file is decoded
+	// as it's written so the errors point to the right file and line.
+	name := filepath.Base(rostiFilePath)
+	report.Errors = append(report.Errors, strictCheck(name, body, &Rostifile{})...)
+	report.Errors = append(report.Errors, strictCheck(name, body, &environmentsCheck{})...)
+
+	if environment != "" && hasEnvironmentFile() {
+		overlayBody, err := ioutil.ReadFile(environmentFilePath())
+		if err != nil {
+			return rostifile, report, errors.Wrap(err, environmentFilePath()+" reading error")
+		}
+		report.Errors = append(report.Errors, strictCheck(filepath.Base(environmentFilePath()), overlayBody, &Rostifile{})...)
+	}
+
+	report.Errors = append(report.Errors, rostifile.Validate()...)
+	report.Warnings = rostifile.Lint()
+	if version < CurrentVersion {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("Rostifile uses format version %d, run \"rostictl rostifile migrate\" to upgrade it to version %d", version, CurrentVersion))
+	}
+
+	return rostifile, report, nil
+}
+
+// environmentsCheck is used for strict decoding of environments defined in Rostifile
+type environmentsCheck struct {
+	Environments map[string]Rostifile   `yaml:"environments"`
+	Other        map[string]interface{} `yaml:",inline"`
+}
+
+// strictCheck decodes the content strictly into out and returns the problems
+// prefixed with name of the file
+func strictCheck(name string, body []byte, out interface{}) []error {
+	errs := []error{}
+
+	err := yaml.UnmarshalStrict(body, out)
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		for _, message := range typeErr.Errors {
+			errs = append(errs, fmt.Errorf("%s: %s", name, yamlTypeRegexp.ReplaceAllString(message, "")))
+		}
+	} else if err != nil {
+		errs = append(errs, fmt.Errorf("%s: %w", name, err))
+	}
+
+	return errs
+}
+
+// Matches "supervisorctl stop|start|restart name [name...]"
+var supervisorctlRegexp = regexp.MustCompile(`supervisorctl\s+(stop|start|restart)((?:\s+[a-zA-Z0-9_:*-]+)+)`)
+
+// supervisorctlPrograms returns programs affected by supervisorctl commands
+// with given action in the commands
+func supervisorctlPrograms(commands []Command, actions ...string) map[string]bool {
+	programs := make(map[string]bool)
+	for _, cmd := range commands {
+		for _, match := range supervisorctlRegexp.FindAllStringSubmatch(cmd.Script, -1) {
+			if contains(actions, match[1]) {
+				for _, name := range strings.Fields(match[2]) {
+					programs[name] = true
+				}
+			}
+		}
+	}
+
+	return programs
+}
+
+// Lint returns warnings about likely mistakes in the Rostifile
+func (r *Rostifile) Lint() []string {
+	warnings := []string{}
+
+	// Processes stopped before the deploy stay stopped when nothing starts them again
+	started := supervisorctlPrograms(r.AfterCommands, "start", "restart")
+	stopped := supervisorctlPrograms(r.BeforeCommands, "stop")
+	names := make([]string, 0, len(stopped))
+	for name := range stopped {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !started[name] && !started["all"] {
+			warnings = append(warnings, "before_commands stop "+name+" but after_commands don't start it again")
+		}
+	}
+
+	// Custom domains should use HTTPS
+	if !r.HTTPS {
+		for _, domain := range r.Domains {
+			if !strings.HasSuffix(domain, ".rostiapp.cz") {
+				warnings = append(warnings, "domain "+domain+" is public but https is disabled, set https: true")
+			}
+		}
+	}
+
+	// Runtime of the application decides what technologies are available,
+	// the list here is only for the common typos
+	if r.Technology != "" && !contains(Technologies, r.Technology) {
+		warnings = append(warnings, "technology "+r.Technology+" is not one of the usual ones: "+strings.Join(Technologies, ", ")+suggest.DidYouMean(r.Technology, Technologies))
+	}
+
+	// Secrets override variables of the same name
+	for _, name := range sortedKeys(r.Secrets) {
+		if _, ok := r.Env[name]; ok {
+			warnings = append(warnings, "variable "+name+" is defined in both env and secrets, the secret is used")
+		}
+	}
+
+	return warnings
+}
+
+// sortedKeys returns keys of the map in alphabetical order
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLint(t *testing.T) {
+	rostifile := Rostifile{
+		Domains:        []string{"test.rostiapp.cz", "example.com"},
+		BeforeCommands: NewCommands("supervisorctl stop app worker"),
+		AfterCommands:  NewCommands("supervisorctl start worker"),
+	}
+
+	assert.Equal(t, []string{
+		"before_commands stop app but after_commands don't start it again",
+		"domain example.com is public but https is disabled, set https: true",
+	}, rostifile.Lint())
+
+	rostifile.HTTPS = true
+	rostifile.AfterCommands = NewCommands("supervisorctl restart all")
+	assert.Equal(t, []string{}, rostifile.Lint())
+
+	// Technology is checked against the runtime, the static list only warns
+	rostifile.Technology = "pyhton"
+	assert.Equal(t, []string{
+		"technology pyhton is not one of the usual ones: python, node, php, ruby, binary (did you mean python?)",
+	}, rostifile.Lint())
+	assert.Empty(t, rostifile.Validate())
+}
+
+func TestCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rostictl")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, FileName)
+	template := filepath.Join(dir, "app.conf")
+	assert.Nil(t, ioutil.WriteFile(template, []byte("host=${ROSTICTL_TEST_MISSING}\n"), 0644))
+	assert.Nil(t, ioutil.WriteFile(path, []byte("version: 2\nname: app\n\nprocesess: []\nfiles:\n  /srv/app/app.conf:\n    source: "+template+"\n"), 0644))
+	assert.Nil(t, ioutil.WriteFile(path+".staging", []byte("name: app-staging\ndomain: staging.example.com\n"), 0644))
+
+	SetFilePath(path)
+	defer SetFilePath("./" + FileName)
+	assert.Nil(t, SetEnvironment("staging"))
+	defer SetEnvironment("")
+
+	_, report, err := Check()
+	assert.Nil(t, err)
+	messages := []string{}
+	for _, err := range report.Errors {
+		messages = append(messages, err.Error())
+	}
+	assert.Contains(t, messages, "Rostifile: line 4: field procesess not found")
+	assert.Contains(t, messages, "Rostifile.staging: line 2: field domain not found")
+	assert.Contains(t, messages, "variable ROSTICTL_TEST_MISSING used in "+template+" is not set, set it or use ${ROSTICTL_TEST_MISSING:-default}")
+}
@@ -0,0 +1,171 @@
+package progress
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+This package shows progress of long running transfers. It wraps a reader
+and prints bytes sent, percent, throughput and ETA while the data are read.
+*/
+
+// How often the progress is printed
+const (
+	terminalInterval = 200 * time.Millisecond
+	plainInterval    = 5 * time.Second
+)
+
+// Reader wraps another reader, prints the progress and optionally limits
+// the bandwidth.
+type Reader struct {
+	// Limit is maximum throughput in bytes per second, zero means no limit
+	Limit int64
+
+	reader    io.Reader
+	total     int64
+	sent      int64
+	output    io.Writer
+	terminal  bool
+	started   time.Time
+	lastPrint time.Time
+}
+
+// NewReader returns reader printing progress into output. Output can be nil
+// when the progress shouldn't be printed. When terminal is true the progress
+// is printed on a single line updated in place, otherwise plain lines are
+// printed periodically.
+func NewReader(reader io.Reader, total int64, output io.Writer, terminal bool) *Reader {
+	return &Reader{
+		reader:   reader,
+		total:    total,
+		output:   output,
+		terminal: terminal,
+		started:  time.Now(),
+	}
+}
+
+// Read implements io.Reader
+func (r *Reader) Read(p []byte) (int, error) {
+	// Smaller chunks keep the limited rate smooth
+	if r.Limit > 0 && int64(len(p)) > r.Limit/10+1 {
+		p = p[:r.Limit/10+1]
+	}
+
+	n, err := r.reader.Read(p)
+	r.sent += int64(n)
+
+	if r.Limit > 0 {
+		expected := time.Duration(float64(r.sent) / float64(r.Limit) * float64(time.Second))
+		elapsed := time.Since(r.started)
+		if expected > elapsed {
+			time.Sleep(expected - elapsed)
+		}
+	}
+
+	interval := plainInterval
+	if r.terminal {
+		interval = terminalInterval
+	}
+	if time.Since(r.lastPrint) >= interval {
+		r.print()
+	}
+
+	return n, err
+}
+
+// Finish prints the final state of the transfer
+func (r *Reader) Finish() {
+	r.print()
+	if r.output != nil && r.terminal {
+		fmt.Fprintln(r.output, "")
+	}
+}
+
+func (r *Reader) print() {
+	r.lastPrint = time.Now()
+	if r.output == nil {
+		return
+	}
+
+	line := r.String()
+	if r.terminal {
+		// Spaces clean leftovers of a longer previous line
+		fmt.Fprintf(r.output, "\r     %-70s", line)
+	} else {
+		fmt.Fprintln(r.output, "     "+line)
+	}
+}
+
+// String returns the current progress as a single line
+func (r *Reader) String() string {
+	elapsed := time.Since(r.started).Seconds()
+	var rate float64
+	if elapsed > 0 {
+		rate = float64(r.sent) / elapsed
+	}
+
+	line := FormatBytes(r.sent)
+	if r.total > 0 {
+		line += " / " + FormatBytes(r.total)
+		line += fmt.Sprintf(" (%d%%)", r.sent*100/r.total)
+	}
+	line += fmt.Sprintf(", %s/s", FormatBytes(int64(rate)))
+
+	if r.total > 0 && rate > 0 && r.sent < r.total {
+		eta := time.Duration(float64(r.total-r.sent) / rate * float64(time.Second))
+		line += ", ETA " + eta.Round(time.Second).String()
+	}
+
+	return line
+}
+
+// FormatBytes returns human readable size
+func FormatBytes(n int64) string {
+	units := []string{"B", "kB", "MB", "GB", "TB"}
+	value := float64(n)
+	unit := 0
+	for value >= 1000 && unit < len(units)-1 {
+		value /= 1000
+		unit++
+	}
+
+	if unit == 0 {
+		return fmt.Sprintf("%d %s", n, units[unit])
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
+
+// ParseRate parses rate like 500K or 2M into bytes per second. Suffixes K,
+// M and G are multiples of 1024.
+func ParseRate(rate string) (int64, error) {
+	rate = strings.ToUpper(strings.TrimSpace(rate))
+	rate = strings.TrimSuffix(rate, "B")
+	if rate == "" {
+		return 0, nil
+	}
+
+	multiplier := int64(1)
+	switch rate[len(rate)-1] {
+	case 'K':
+		multiplier = 1024
+	case 'M':
+		multiplier = 1024 * 1024
+	case 'G':
+		multiplier = 1024 * 1024 * 1024
+	}
+	if multiplier > 1 {
+		rate = rate[:len(rate)-1]
+	}
+
+	value, err := strconv.ParseFloat(rate, 64)
+	if err != nil || value < 0 {
+		return 0, errors.New("invalid rate, use a number of bytes per second with optional K, M or G suffix, e.g. 500K")
+	}
+
+	return int64(value * float64(multiplier)), nil
+}
@@ -0,0 +1,52 @@
+package progress
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRate(t *testing.T) {
+	for rate, expected := range map[string]int64{
+		"":     0,
+		"1000": 1000,
+		"500K": 500 * 1024,
+		"2M":   2 * 1024 * 1024,
+		"1.5m": 1572864,
+		"1G":   1024 * 1024 * 1024,
+		"64kb": 64 * 1024,
+	} {
+		value, err := ParseRate(rate)
+		assert.Nil(t, err, rate)
+		assert.Equal(t, expected, value, rate)
+	}
+
+	_, err := ParseRate("fast")
+	assert.NotNil(t, err)
+}
+
+func TestReader(t *testing.T) {
+	output := &bytes.Buffer{}
+	data := strings.Repeat("x", 10000)
+
+	reader := NewReader(strings.NewReader(data), int64(len(data)), output, false)
+	reader.Limit = 50000
+
+	started := time.Now()
+	body, err := ioutil.ReadAll(reader)
+	reader.Finish()
+
+	assert.Nil(t, err)
+	assert.Equal(t, data, string(body))
+	assert.True(t, time.Since(started) >= 150*time.Millisecond)
+	assert.Contains(t, output.String(), "10.0 kB / 10.0 kB (100%)")
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "999 B", FormatBytes(999))
+	assert.Equal(t, "1.5 MB", FormatBytes(1500000))
+}
@@ -0,0 +1,137 @@
+package secrets
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+/*
+This package encrypts and decrypts values of secrets section of Rostifile.
+Values are encrypted by NaCl secretbox with a key kept outside of the
+project, either in an environment variable or in the config directory.
+*/
+
+// KeyEnvVariable is name of environment variable that can hold the key
+const KeyEnvVariable = "ROSTI_SECRETS_KEY"
+
+// Prefix of every encrypted value
+const encryptedPrefix = "secretbox:"
+
+const keySize = 32
+const nonceSize = 24
+
+// ErrNoKey is returned when the key hasn't been found
+var ErrNoKey = errors.New("secrets key not found")
+
+// Key is a symmetric key used to encrypt the secrets
+type Key [keySize]byte
+
+// String returns base64 encoded key
+func (k *Key) String() string {
+	return base64.StdEncoding.EncodeToString(k[:])
+}
+
+// GenerateKey returns a new random key
+func GenerateKey() (*Key, error) {
+	key := Key{}
+	_, err := io.ReadFull(rand.Reader, key[:])
+	if err != nil {
+		return nil, fmt.Errorf("generating key error: %w", err)
+	}
+
+	return &key, nil
+}
+
+// ParseKey decodes base64 encoded key
+func ParseKey(encoded string) (*Key, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("decoding key error: %w", err)
+	}
+	if len(raw) != keySize {
+		return nil, fmt.Errorf("key has to be %d bytes long", keySize)
+	}
+
+	key := Key{}
+	copy(key[:], raw)
+
+	return &key, nil
+}
+
+// LoadKey returns key from the environment variable or from the given file.
+// ErrNoKey is returned if there is no key in either of them.
+func LoadKey(path string) (*Key, error) {
+	if encoded := os.Getenv(KeyEnvVariable); encoded != "" {
+		return ParseKey(encoded)
+	}
+
+	body, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNoKey
+	} else if err != nil {
+		return nil, fmt.Errorf("reading key error: %w", err)
+	}
+
+	return ParseKey(string(body))
+}
+
+// SaveKey writes the key into the given file readable only by its owner
+func SaveKey(path string, key *Key) error {
+	err := ioutil.WriteFile(path, []byte(key.String()+"\n"), 0600)
+	if err != nil {
+		return fmt.Errorf("writing key error: %w", err)
+	}
+
+	return nil
+}
+
+// IsEncrypted returns true if the value looks like an encrypted secret
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// Encrypt encrypts the plaintext with the key
+func Encrypt(key *Key, plaintext string) (string, error) {
+	var nonce [nonceSize]byte
+	_, err := io.ReadFull(rand.Reader, nonce[:])
+	if err != nil {
+		return "", fmt.Errorf("generating nonce error: %w", err)
+	}
+
+	box := secretbox.Seal(nonce[:], []byte(plaintext), &nonce, (*[keySize]byte)(key))
+
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(box), nil
+}
+
+// Decrypt returns plaintext of the encrypted value
+func Decrypt(key *Key, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", errors.New("value is not encrypted")
+	}
+
+	box, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("decoding value error: %w", err)
+	}
+	if len(box) < nonceSize+secretbox.Overhead {
+		return "", errors.New("encrypted value is too short")
+	}
+
+	var nonce [nonceSize]byte
+	copy(nonce[:], box[:nonceSize])
+
+	plaintext, ok := secretbox.Open(nil, box[nonceSize:], &nonce, (*[keySize]byte)(key))
+	if !ok {
+		return "", errors.New("decryption failed, wrong key?")
+	}
+
+	return string(plaintext), nil
+}
@@ -0,0 +1,38 @@
+package secrets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	key, err := GenerateKey()
+	assert.Nil(t, err)
+
+	encrypted, err := Encrypt(key, "db-password")
+	assert.Nil(t, err)
+	assert.True(t, IsEncrypted(encrypted))
+	assert.NotContains(t, encrypted, "db-password")
+
+	plaintext, err := Decrypt(key, encrypted)
+	assert.Nil(t, err)
+	assert.Equal(t, "db-password", plaintext)
+
+	otherKey, err := GenerateKey()
+	assert.Nil(t, err)
+	_, err = Decrypt(otherKey, encrypted)
+	assert.NotNil(t, err)
+}
+
+func TestParseKey(t *testing.T) {
+	key, err := GenerateKey()
+	assert.Nil(t, err)
+
+	parsed, err := ParseKey(key.String())
+	assert.Nil(t, err)
+	assert.Equal(t, key, parsed)
+
+	_, err = ParseKey("c2hvcnQ=")
+	assert.NotNil(t, err)
+}
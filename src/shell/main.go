@@ -0,0 +1,13 @@
+package shell
+
+import "strings"
+
+// Quote returns the string quoted for POSIX shell so it's passed as a single
+// argument without any expansion.
+func Quote(s string) string {
+	if s == "" {
+		return "''"
+	}
+
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,29 @@
+package shell
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuote(t *testing.T) {
+	assert.Equal(t, "''", Quote(""))
+	assert.Equal(t, "'echo hello'", Quote("echo hello"))
+	assert.Equal(t, `'echo '\''it works'\'''`, Quote("echo 'it works'"))
+}
+
+func TestQuoteInShell(t *testing.T) {
+	inputs := []string{
+		"it's",
+		`"double" and 'single' quotes`,
+		"$HOME `date` $(date) \\ ; | &",
+		"multiple\nlines",
+	}
+
+	for _, input := range inputs {
+		out, err := exec.Command("/bin/sh", "-c", "printf %s "+Quote(input)).Output()
+		assert.Nil(t, err)
+		assert.Equal(t, input, string(out))
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	pathpkg "path"
 	"strings"
 
 	"github.com/pkg/sftp"
@@ -127,6 +128,95 @@ func (c *Client) SendFile(path string, content string) error {
 	return err
 }
 
+// WriteFile uploads a content into a remote path, creates its parent
+// directories and sets permissions of the file.
+func (c *Client) WriteFile(path string, content []byte, mode os.FileMode) error {
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	sftpClient, err := sftp.NewClient(client)
+	if err != nil {
+		return err
+	}
+	defer sftpClient.Close()
+
+	err = sftpClient.MkdirAll(pathpkg.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	f, err := sftpClient.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(content)
+	if err != nil {
+		return err
+	}
+
+	return f.Chmod(mode)
+}
+
+// AppendFile appends a content to a remote file. The file and its parent
+// directory are created when they don't exist.
+func (c *Client) AppendFile(path string, content string) error {
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	sftpClient, err := sftp.NewClient(client)
+	if err != nil {
+		return err
+	}
+	defer sftpClient.Close()
+
+	err = sftpClient.MkdirAll(pathpkg.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	f, err := sftpClient.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write([]byte(content))
+
+	return err
+}
+
+// ReadFile returns content of a remote file. If the file doesn't exist
+// the returned error satisfies os.IsNotExist.
+func (c *Client) ReadFile(path string) ([]byte, error) {
+	client, err := c.client()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	sftpClient, err := sftp.NewClient(client)
+	if err != nil {
+		return nil, err
+	}
+	defer sftpClient.Close()
+
+	f, err := sftpClient.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ioutil.ReadAll(f)
+}
+
 // Run runs a command on the remote server.
 func (c *Client) Run(command string) (*bytes.Buffer, error) {
 	client, err := c.client()
@@ -4,11 +4,28 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"gopkg.in/yaml.v2"
 )
 
-const rostiStateFilePath = "./.rosti.state"
+var rostiStateFilePath = "./.rosti.state"
+
+// SetEnvironment switches to the state file of the given environment, each
+// environment is deployed into its own application.
+func SetEnvironment(name string) {
+	if name == "" {
+		rostiStateFilePath = "./.rosti.state"
+	} else {
+		rostiStateFilePath = "./.rosti." + name + ".state"
+	}
+}
+
+// Exists returns true if the state file exists
+func Exists() bool {
+	_, err := os.Stat(rostiStateFilePath)
+	return err == nil
+}
 
 // Load returns parsed RostiState
 func Load() (*RostiState, error) {
@@ -36,6 +53,26 @@ func Load() (*RostiState, error) {
 	return &rostiStateFile, nil
 }
 
+// LoadFrom returns parsed RostiState of the project in the given directory.
+// Unlike Load it doesn't create the file when it's missing.
+func LoadFrom(dir string) (*RostiState, error) {
+	rostiStateFile := RostiState{}
+
+	body, err := ioutil.ReadFile(filepath.Join(dir, rostiStateFilePath))
+	if os.IsNotExist(err) {
+		return &rostiStateFile, nil
+	} else if err != nil {
+		return &rostiStateFile, fmt.Errorf("rosti state file reading error: %w", err)
+	}
+
+	err = yaml.Unmarshal(body, &rostiStateFile)
+	if err != nil {
+		return &rostiStateFile, fmt.Errorf("rosti state file parsing error: %w", err)
+	}
+
+	return &rostiStateFile, nil
+}
+
 // Write writes state structure content into a designated path
 func Write(state *RostiState) error {
 	body, err := yaml.Marshal(state)
@@ -53,5 +90,5 @@ func Write(state *RostiState) error {
 
 // Remove removes state file from the current working directory.
 func Remove() error {
-	return os.Remove(".rosti.state")
+	return os.Remove(rostiStateFilePath)
 }
@@ -0,0 +1,71 @@
+package suggest
+
+import "strings"
+
+/*
+This package finds the closest match of a mistyped value so error messages
+can ask "did you mean ...?".
+*/
+
+// Closest returns the option closest to the word or empty string when no
+// option is similar enough
+func Closest(word string, options []string) string {
+	var closest string
+	best := len(word)/3 + 1
+	if best < 2 {
+		best = 2
+	}
+
+	for _, option := range options {
+		d := distance(strings.ToLower(word), strings.ToLower(option))
+		if d <= best && (closest == "" || d < distance(strings.ToLower(word), strings.ToLower(closest))) {
+			closest = option
+		}
+	}
+
+	return closest
+}
+
+// DidYouMean returns " (did you mean X?)" for the closest option or empty string
+func DidYouMean(word string, options []string) string {
+	closest := Closest(word, options)
+	if closest == "" || closest == word {
+		return ""
+	}
+
+	return " (did you mean " + closest + "?)"
+}
+
+// distance returns Levenshtein distance of the two strings
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,25 @@
+package suggest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClosest(t *testing.T) {
+	options := []string{"python", "node", "php", "ruby", "binary"}
+
+	assert.Equal(t, "python", Closest("pyhton", options))
+	assert.Equal(t, "node", Closest("nodejs", options))
+	assert.Equal(t, "php", Closest("PHP", options))
+	assert.Equal(t, "", Closest("golang", options))
+
+	assert.Equal(t, " (did you mean 3.10.4?)", DidYouMean("3.10.5", []string{"3.9.12", "3.10.4"}))
+	assert.Equal(t, "", DidYouMean("ruby", options))
+}
+
+func TestDistance(t *testing.T) {
+	assert.Equal(t, 0, distance("abc", "abc"))
+	assert.Equal(t, 3, distance("kitten", "sitting"))
+	assert.Equal(t, 4, distance("", "node"))
+}
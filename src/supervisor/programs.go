@@ -0,0 +1,80 @@
+package supervisor
+
+import (
+	"sort"
+	"strings"
+)
+
+// Changes lists names of programs that differ between two configs
+type Changes struct {
+	Added   []string
+	Changed []string
+	Removed []string
+}
+
+// Empty returns true when there are no changes
+func (c *Changes) Empty() bool {
+	return len(c.Added) == 0 && len(c.Changed) == 0 && len(c.Removed) == 0
+}
+
+// Programs returns content of every [program:x] section of the config
+// mapped by the program name. Comments and empty lines are ignored.
+func Programs(content string) map[string]string {
+	programs := make(map[string]string)
+
+	var name string
+	var lines []string
+	flush := func() {
+		if name != "" {
+			programs[name] = strings.TrimSpace(strings.Join(lines, "\n"))
+		}
+		name = ""
+		lines = nil
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			flush()
+			section := strings.Trim(trimmed, "[]")
+			if strings.HasPrefix(section, "program:") {
+				name = strings.TrimPrefix(section, "program:")
+			}
+			continue
+		}
+		if name != "" && trimmed != "" && !strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(trimmed, ";") {
+			lines = append(lines, trimmed)
+		}
+	}
+	flush()
+
+	return programs
+}
+
+// Diff returns programs added, changed and removed in the new config
+func Diff(oldContent, newContent string) Changes {
+	changes := Changes{}
+
+	oldPrograms := Programs(oldContent)
+	newPrograms := Programs(newContent)
+
+	for name, body := range newPrograms {
+		oldBody, ok := oldPrograms[name]
+		if !ok {
+			changes.Added = append(changes.Added, name)
+		} else if oldBody != body {
+			changes.Changed = append(changes.Changed, name)
+		}
+	}
+	for name := range oldPrograms {
+		if _, ok := newPrograms[name]; !ok {
+			changes.Removed = append(changes.Removed, name)
+		}
+	}
+
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Changed)
+	sort.Strings(changes.Removed)
+
+	return changes
+}
@@ -0,0 +1,52 @@
+package supervisor
+
+import (
+	"testing"
+
+	"github.com/rosti-cz/cli/src/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	oldConfig := `# This file is gonna be rewritten by rostictl
+
+[program:app]
+command=/srv/app/app
+directory=/srv/app
+
+[program:worker]
+command=/srv/app/worker
+
+[program:old]
+command=/srv/app/old
+`
+	newConfig := `# This file is gonna be rewritten by rostictl
+
+[program:app]
+command=/srv/app/app
+directory=/srv/app
+
+[program:worker]
+command=/srv/app/worker --verbose
+
+[program:new]
+command=/srv/app/new
+`
+
+	changes := Diff(oldConfig, newConfig)
+	assert.Equal(t, []string{"new"}, changes.Added)
+	assert.Equal(t, []string{"worker"}, changes.Changed)
+	assert.Equal(t, []string{"old"}, changes.Removed)
+	assert.False(t, changes.Empty())
+
+	changes = Diff(newConfig, newConfig)
+	assert.True(t, changes.Empty())
+}
+
+func TestDiffEmptyConfig(t *testing.T) {
+	rendered, err := Render(&parser.Rostifile{})
+	assert.Nil(t, err)
+
+	changes := Diff("[program:app]\ncommand=/srv/app/app\n", rendered)
+	assert.Equal(t, []string{"app"}, changes.Removed)
+}
@@ -0,0 +1,157 @@
+package supervisor
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/rosti-cz/cli/src/parser"
+)
+
+// ConfigPath is location of the config file managed by rostictl
+const ConfigPath = "/srv/conf/supervisor.d/rostictl.conf"
+
+// PATH used by the processes and deploy commands
+const PATH = "/srv/bin/primary_tech:/usr/local/bin:/usr/bin:/bin:/srv/.npm-packages/bin"
+
+// EnvFilePath is the file with environment variables of the application in the container
+const EnvFilePath = "/srv/app/.env"
+
+// ShellEnv is a one line shell snippet setting up the same PATH and environment
+// variables the processes have, it's used by deploy commands and cron jobs
+const ShellEnv = "export PATH='" + PATH + "'; set -a; [ -f " + EnvFilePath + " ] && . " + EnvFilePath + "; set +a"
+
+const configTemplate = `# This file is gonna be rewritten by rostictl
+{{range .}}
+[program:{{.Name}}]
+command={{.Command}}
+environment={{.Environment}}
+autostart=true
+autorestart={{.AutoRestart}}
+directory={{.Directory}}
+process_name={{.ProcessName}}
+stdout_logfile={{.LogFile}}
+stdout_logfile_maxbytes={{.LogMaxBytes}}
+stdout_logfile_backups={{.LogBackups}}
+stdout_capture_maxbytes=2MB
+stdout_events_enabled=false
+redirect_stderr=true
+{{if .StopKillAsGroup}}stopasgroup=true
+killasgroup=true
+{{end}}{{if gt .Numprocs 1}}numprocs={{.Numprocs}}
+{{end}}{{if .Priority}}priority={{.Priority}}
+{{end}}{{if .StartSecs}}startsecs={{.StartSecs}}
+{{end}}{{if .StopWaitSecs}}stopwaitsecs={{.StopWaitSecs}}
+{{end}}{{if .StopSignal}}stopsignal={{.StopSignal}}
+{{end}}{{end}}
+`
+
+var tmpl = template.Must(template.New("supervisor").Parse(configTemplate))
+
+// program holds values of one program section already escaped for the config file
+type program struct {
+	Name            string
+	Command         string
+	Environment     string
+	AutoRestart     string
+	Directory       string
+	ProcessName     string
+	LogFile         string
+	LogMaxBytes     string
+	LogBackups      int
+	StopKillAsGroup bool
+	Numprocs        int
+	Priority        int
+	StartSecs       string
+	StopWaitSecs    int
+	StopSignal      string
+}
+
+// Environment returns value of the environment option. Values are quoted
+// and escaped so they can contain commas, quotes and percent signs.
+func Environment(env map[string]string) string {
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `%`, `%%`)
+
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	items := []string{`PATH="` + PATH + `"`}
+	for _, key := range keys {
+		if key == "PATH" {
+			continue
+		}
+		items = append(items, key+`="`+escaper.Replace(env[key])+`"`)
+	}
+
+	return strings.Join(items, ",")
+}
+
+// Supervisor expands %(name)s expressions in all values, literal % has to be doubled
+var percentEscaper = strings.NewReplacer(`%`, `%%`)
+
+// Render returns content of the config file with all processes defined in Rostifile
+func Render(rostifile *parser.Rostifile) (string, error) {
+	programs := make([]program, 0, len(rostifile.Processes))
+
+	for _, process := range rostifile.Processes {
+		name := percentEscaper.Replace(process.Name)
+		p := program{
+			Name:            process.Name,
+			Command:         percentEscaper.Replace(process.Command),
+			Environment:     Environment(rostifile.ProcessEnv(process)),
+			AutoRestart:     "true",
+			Directory:       "/srv/app",
+			ProcessName:     name,
+			LogFile:         "/srv/log/" + name + ".log",
+			LogMaxBytes:     "2MB",
+			LogBackups:      5,
+			StopKillAsGroup: process.StopKillAsGroup,
+			Numprocs:        process.Numprocs,
+			Priority:        process.Priority,
+			StopWaitSecs:    process.StopWaitSecs,
+			StopSignal:      percentEscaper.Replace(process.StopSignal),
+		}
+
+		if process.AutoRestart != "" {
+			p.AutoRestart = percentEscaper.Replace(process.AutoRestart)
+		}
+		if process.Directory != "" {
+			p.Directory = percentEscaper.Replace(process.Directory)
+		}
+		if process.Numprocs > 1 {
+			p.ProcessName = name + "_%(process_num)02d"
+			p.LogFile = "/srv/log/" + name + "_%(process_num)02d.log"
+		}
+		if process.LogMaxBytes != "" {
+			p.LogMaxBytes = percentEscaper.Replace(process.LogMaxBytes)
+		}
+		if process.LogBackups != nil {
+			p.LogBackups = *process.LogBackups
+		}
+		if process.StartSecs != nil {
+			p.StartSecs = strconv.Itoa(*process.StartSecs)
+		}
+
+		for _, value := range []string{p.Name, p.Command, p.Environment, p.AutoRestart, p.Directory, p.LogMaxBytes, p.StopSignal} {
+			if strings.ContainsAny(value, "\r\n") {
+				return "", errors.New("options of process " + process.Name + " can't contain new lines")
+			}
+		}
+
+		programs = append(programs, p)
+	}
+
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, programs)
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
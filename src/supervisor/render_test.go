@@ -0,0 +1,120 @@
+package supervisor
+
+import (
+	"testing"
+
+	"github.com/rosti-cz/cli/src/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderDefaults(t *testing.T) {
+	rostifile := parser.Rostifile{
+		Processes: []parser.Process{
+			{Name: "app", Command: "/srv/app/app"},
+			{Name: "worker", Command: "/srv/app/worker", StopKillAsGroup: true},
+		},
+	}
+
+	content, err := Render(&rostifile)
+	assert.Nil(t, err)
+	assert.Equal(t, `# This file is gonna be rewritten by rostictl
+
+[program:app]
+command=/srv/app/app
+environment=PATH="/srv/bin/primary_tech:/usr/local/bin:/usr/bin:/bin:/srv/.npm-packages/bin"
+autostart=true
+autorestart=true
+directory=/srv/app
+process_name=app
+stdout_logfile=/srv/log/app.log
+stdout_logfile_maxbytes=2MB
+stdout_logfile_backups=5
+stdout_capture_maxbytes=2MB
+stdout_events_enabled=false
+redirect_stderr=true
+
+[program:worker]
+command=/srv/app/worker
+environment=PATH="/srv/bin/primary_tech:/usr/local/bin:/usr/bin:/bin:/srv/.npm-packages/bin"
+autostart=true
+autorestart=true
+directory=/srv/app
+process_name=worker
+stdout_logfile=/srv/log/worker.log
+stdout_logfile_maxbytes=2MB
+stdout_logfile_backups=5
+stdout_capture_maxbytes=2MB
+stdout_events_enabled=false
+redirect_stderr=true
+stopasgroup=true
+killasgroup=true
+
+`, content)
+}
+
+func TestRenderOptions(t *testing.T) {
+	startSecs := 0
+	logBackups := 10
+	rostifile := parser.Rostifile{
+		Env: map[string]string{"MODE": "production", "DEBUG": "1"},
+		Processes: []parser.Process{
+			{
+				Name:         "worker",
+				Command:      "/srv/app/worker",
+				Env:          map[string]string{"DEBUG": "0"},
+				Numprocs:     3,
+				Directory:    "/srv/app/worker",
+				Priority:     10,
+				StartSecs:    &startSecs,
+				StopWaitSecs: 30,
+				StopSignal:   "INT",
+				AutoRestart:  "unexpected",
+				LogMaxBytes:  "10MB",
+				LogBackups:   &logBackups,
+			},
+		},
+	}
+
+	content, err := Render(&rostifile)
+	assert.Nil(t, err)
+	assert.Contains(t, content, "\nenvironment=PATH=\""+PATH+"\",DEBUG=\"0\",MODE=\"production\"\n")
+	assert.Contains(t, content, "\nautorestart=unexpected\n")
+	assert.Contains(t, content, "\ndirectory=/srv/app/worker\n")
+	assert.Contains(t, content, "\nprocess_name=worker_%(process_num)02d\n")
+	assert.Contains(t, content, "\nstdout_logfile=/srv/log/worker_%(process_num)02d.log\n")
+	assert.Contains(t, content, "\nstdout_logfile_maxbytes=10MB\n")
+	assert.Contains(t, content, "\nstdout_logfile_backups=10\n")
+	assert.Contains(t, content, "\nnumprocs=3\n")
+	assert.Contains(t, content, "\npriority=10\n")
+	assert.Contains(t, content, "\nstartsecs=0\n")
+	assert.Contains(t, content, "\nstopwaitsecs=30\n")
+	assert.Contains(t, content, "\nstopsignal=INT\n")
+}
+
+func TestRenderEscaping(t *testing.T) {
+	assert.Equal(
+		t,
+		`PATH="`+PATH+`",A="say \"hi\", 100%% \\o/"`,
+		Environment(map[string]string{"A": `say "hi", 100% \o/`, "PATH": "/ignored"}),
+	)
+
+	rostifile := parser.Rostifile{
+		Processes: []parser.Process{
+			{Name: "app", Command: "/srv/app/app\n[program:evil]"},
+		},
+	}
+	_, err := Render(&rostifile)
+	assert.NotNil(t, err)
+
+	// Percent signs are escaped in all values, not only in the environment
+	rostifile = parser.Rostifile{
+		Processes: []parser.Process{
+			{Name: "app", Command: "date +%Y-%m-%d", Directory: "/srv/app/100%", Numprocs: 2},
+		},
+	}
+	config, err := Render(&rostifile)
+	assert.Nil(t, err)
+	assert.Contains(t, config, "command=date +%%Y-%%m-%%d\n")
+	assert.Contains(t, config, "directory=/srv/app/100%%\n")
+	assert.Contains(t, config, "process_name=app_%(process_num)02d\n")
+}
@@ -0,0 +1,74 @@
+package supervisor
+
+import (
+	"strings"
+
+	"github.com/rosti-cz/cli/src/parser"
+)
+
+// States of supervisor's processes
+const (
+	StateRunning  = "RUNNING"
+	StateStarting = "STARTING"
+	StateBackoff  = "BACKOFF"
+	StateFatal    = "FATAL"
+	StateExited   = "EXITED"
+)
+
+// ProcessStatus is one line of `supervisorctl status` output
+type ProcessStatus struct {
+	Name        string // name of the process, group:name when the process runs in more instances
+	State       string
+	Description string
+}
+
+// ParseStatus parses output of `supervisorctl status` command
+func ParseStatus(output string) []ProcessStatus {
+	statuses := []ProcessStatus{}
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		statuses = append(statuses, ProcessStatus{
+			Name:        fields[0],
+			State:       fields[1],
+			Description: strings.Join(fields[2:], " "),
+		})
+	}
+
+	return statuses
+}
+
+// StatusesOf returns statuses of all instances of the process
+func StatusesOf(process parser.Process, statuses []ProcessStatus) []ProcessStatus {
+	result := []ProcessStatus{}
+	for _, status := range statuses {
+		if status.Name == process.Name || strings.HasPrefix(status.Name, process.Name+":") {
+			result = append(result, status)
+		}
+	}
+
+	return result
+}
+
+// IsUp returns true when the state is what's expected from the process after deploy.
+// Processes that are not restarted automatically can also exit.
+func IsUp(process parser.Process, state string) bool {
+	if state == StateRunning {
+		return true
+	}
+
+	return state == StateExited && (process.AutoRestart == "false" || process.AutoRestart == "unexpected")
+}
+
+// LogFile returns path of the log file of the process. When the process runs
+// in more instances, log of the first one is returned.
+func LogFile(process parser.Process) string {
+	if process.Numprocs > 1 {
+		return "/srv/log/" + process.Name + "_00.log"
+	}
+	return "/srv/log/" + process.Name + ".log"
+}
@@ -0,0 +1,42 @@
+package supervisor
+
+import (
+	"testing"
+
+	"github.com/rosti-cz/cli/src/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+const statusOutput = `app                              RUNNING   pid 123, uptime 0:01:05
+worker:worker_00                 FATAL     Exited too quickly (process log may have details)
+worker:worker_01                 BACKOFF   Exited too quickly (process log may have details)
+other                            STOPPED   Not started
+`
+
+func TestParseStatus(t *testing.T) {
+	statuses := ParseStatus(statusOutput)
+
+	assert.Len(t, statuses, 4)
+	assert.Equal(t, ProcessStatus{Name: "app", State: StateRunning, Description: "pid 123, uptime 0:01:05"}, statuses[0])
+	assert.Equal(t, StateFatal, statuses[1].State)
+}
+
+func TestStatusesOf(t *testing.T) {
+	statuses := ParseStatus(statusOutput)
+
+	app := StatusesOf(parser.Process{Name: "app"}, statuses)
+	assert.Len(t, app, 1)
+
+	worker := StatusesOf(parser.Process{Name: "worker", Numprocs: 2}, statuses)
+	assert.Len(t, worker, 2)
+	assert.Equal(t, StateBackoff, worker[1].State)
+
+	assert.Len(t, StatusesOf(parser.Process{Name: "work"}, statuses), 0)
+}
+
+func TestIsUp(t *testing.T) {
+	assert.True(t, IsUp(parser.Process{Name: "app"}, StateRunning))
+	assert.False(t, IsUp(parser.Process{Name: "app"}, StateExited))
+	assert.True(t, IsUp(parser.Process{Name: "app", AutoRestart: "false"}, StateExited))
+	assert.False(t, IsUp(parser.Process{Name: "app"}, StateBackoff))
+}
@@ -0,0 +1,125 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+/*
+Workspace describes multiple applications deployed from one repository.
+Every application lives in its own directory with its own Rostifile and
+state file.
+*/
+
+// FilePath is location of the workspace file
+const FilePath = "./Rostiworkspace"
+
+var appNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// App is a single application of the workspace
+type App struct {
+	// Directory with Rostifile of the application, relative to the workspace file
+	Path string `yaml:"path"`
+}
+
+// UnmarshalYAML allows to use just the path instead of the whole structure
+func (a *App) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var path string
+	err := unmarshal(&path)
+	if err == nil {
+		a.Path = path
+		return nil
+	}
+
+	type plain App
+	return unmarshal((*plain)(a))
+}
+
+// Workspace is content of the workspace file
+type Workspace struct {
+	Apps map[string]App `yaml:"apps"`
+}
+
+// Exists returns true if there is a workspace file in the current directory
+func Exists() bool {
+	_, err := os.Stat(FilePath)
+	return err == nil
+}
+
+// Load returns parsed and validated workspace file
+func Load() (*Workspace, error) {
+	workspace := Workspace{}
+
+	body, err := ioutil.ReadFile(FilePath)
+	if err != nil {
+		return &workspace, fmt.Errorf("workspace file reading error: %w", err)
+	}
+
+	err = yaml.Unmarshal(body, &workspace)
+	if err != nil {
+		return &workspace, fmt.Errorf("workspace file parsing error: %w", err)
+	}
+
+	return &workspace, workspace.Validate()
+}
+
+// Validate checks names and paths of the applications
+func (w *Workspace) Validate() error {
+	if len(w.Apps) == 0 {
+		return errors.New("workspace file doesn't define any apps")
+	}
+
+	for _, name := range w.Names() {
+		if !appNameRegexp.MatchString(name) {
+			return fmt.Errorf("app name %s can contain only these characters: a-zA-Z0-9_-", name)
+		}
+		if w.Apps[name].Path == "" {
+			return fmt.Errorf("app %s has no path", name)
+		}
+		if filepath.IsAbs(w.Apps[name].Path) {
+			return fmt.Errorf("path of app %s has to be relative to the workspace file", name)
+		}
+	}
+
+	return nil
+}
+
+// Names returns sorted names of all applications
+func (w *Workspace) Names() []string {
+	names := make([]string, 0, len(w.Apps))
+	for name := range w.Apps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Select returns names of the given applications in the order they were
+// given or all of them when names are empty.
+func (w *Workspace) Select(names []string) ([]string, error) {
+	if len(names) == 0 {
+		return w.Names(), nil
+	}
+
+	seen := make(map[string]bool)
+	selected := []string{}
+	for _, name := range names {
+		if _, ok := w.Apps[name]; !ok {
+			return nil, fmt.Errorf("app %s is not defined in the workspace file", name)
+		}
+		if !seen[name] {
+			selected = append(selected, name)
+			seen[name] = true
+		}
+	}
+
+	return selected, nil
+}
@@ -0,0 +1,25 @@
+package workspace
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestWorkspace(t *testing.T) {
+	workspace := Workspace{}
+	err := yaml.Unmarshal([]byte("apps:\n  worker: services/worker\n  api:\n    path: services/api\n"), &workspace)
+	assert.Nil(t, err)
+	assert.Nil(t, workspace.Validate())
+
+	assert.Equal(t, "services/api", workspace.Apps["api"].Path)
+	assert.Equal(t, []string{"api", "worker"}, workspace.Names())
+
+	selected, err := workspace.Select([]string{"worker", "api", "worker"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"worker", "api"}, selected)
+
+	_, err = workspace.Select([]string{"frontend"})
+	assert.NotNil(t, err)
+}
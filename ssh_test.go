@@ -6,7 +6,7 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-const SSHKeysTestDirectory = "contrib/test_ssh_keys/"
+const SSHKeysTestDirectory = "contrib/testimage/test_ssh_keys/"
 
 func TestGetLocalSSHKeys(t *testing.T) {
 	keys, err := getLocalSSHKeys(SSHKeysTestDirectory)
@@ -7,19 +7,103 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"os/exec"
+	"os/signal"
 	"os/user"
 	"path"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/fatih/color"
+	"github.com/rosti-cz/cli/src/config"
+	"github.com/rosti-cz/cli/src/crontab"
+	"github.com/rosti-cz/cli/src/history"
+	"github.com/rosti-cz/cli/src/lock"
 	"github.com/rosti-cz/cli/src/parser"
 	"github.com/rosti-cz/cli/src/rostiapi"
+	"github.com/rosti-cz/cli/src/secrets"
+	"github.com/rosti-cz/cli/src/shell"
+	"github.com/rosti-cz/cli/src/ssh"
 	"github.com/rosti-cz/cli/src/state"
+	"github.com/rosti-cz/cli/src/suggest"
+	"github.com/rosti-cz/cli/src/supervisor"
+	"github.com/rosti-cz/cli/src/workspace"
 	"github.com/urfave/cli/v2"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
+// walkSource walks through the source directory and calls fn for every file
+// and directory that is not excluded.
+func walkSource(source string, exclude []string, fn filepath.WalkFunc) error {
+	return filepath.Walk(source,
+		func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			for _, excludedItem := range exclude {
+				if info.IsDir() && info.Name() == excludedItem {
+					return filepath.SkipDir
+				} else if info.Name() == excludedItem {
+					return nil
+				}
+			}
+
+			return fn(path, info, nil)
+		})
+}
+
+// runBuildCommands runs build commands from Rostifile locally in the source
+// directory. The output is streamed into the terminal.
+func runBuildCommands(rostifile *parser.Rostifile) error {
+	for _, cmd := range rostifile.BuildCommands {
+		cYellow.Print(".. building: ")
+		cWhite.Println(cmd)
+
+		var command *exec.Cmd
+		if runtime.GOOS == "windows" {
+			command = exec.Command("cmd", "/C", cmd)
+		} else {
+			command = exec.Command("/bin/sh", "-c", cmd)
+		}
+		command.Dir = rostifile.SourcePath
+		command.Stdout = os.Stdout
+		command.Stderr = os.Stderr
+
+		err := command.Run()
+		if err != nil {
+			return fmt.Errorf("build command \"%s\" failed: %w", cmd, err)
+		}
+	}
+
+	info, err := os.Stat(rostifile.ArchivePath())
+	if err != nil {
+		return fmt.Errorf("directory to upload not found: %w", err)
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", rostifile.ArchivePath())
+	}
+
+	return nil
+}
+
+// countSourceFiles returns number of regular files that would be uploaded
+func countSourceFiles(source string, exclude []string) (int, error) {
+	var count int
+	err := walkSource(source, exclude, func(path string, info os.FileInfo, err error) error {
+		if info.Mode().IsRegular() {
+			count++
+		}
+		return nil
+	})
+
+	return count, err
+}
+
 func createArchive(source, target string, exclude []string) error {
 	tarfile, err := os.Create(target)
 	if err != nil {
@@ -40,19 +124,8 @@ func createArchive(source, target string, exclude []string) error {
 		baseDir = filepath.Base(source)
 	}
 
-	return filepath.Walk(source,
+	return walkSource(source, exclude,
 		func(path string, info os.FileInfo, err error) error {
-			for _, excludedItem := range exclude {
-				if info.IsDir() && info.Name() == excludedItem {
-					return filepath.SkipDir
-				} else if info.Name() == excludedItem {
-					return nil
-				}
-			}
-
-			if err != nil {
-				return err
-			}
 			header, err := tar.FileInfoHeader(info, info.Name())
 			if err != nil {
 				return err
@@ -177,6 +250,361 @@ func findSSHKey() (string, string, error) {
 	return privateKeyPath, publicKeyPath, nil
 }
 
+// ensureSSHKey makes sure the state file points to an existing SSH key
+func ensureSSHKey(appState *state.RostiState) error {
+	if len(appState.SSHKeyPath) == 0 {
+		cYellow.Println(".. SSH key not found in the state file, trying to figure this out")
+		privateSSHKeyPath, _, err := findSSHKey()
+		if err != nil {
+			return fmt.Errorf("SSH key problem: %w", err)
+		}
+		appState.SSHKeyPath = privateSSHKeyPath
+	} else {
+		_, err := os.Stat(appState.SSHKeyPath)
+		if os.IsNotExist(err) {
+			cYellow.Println(".. SSH key configured in state file but the file doesn't not exist, trying to figure this out")
+			privateSSHKeyPath, _, err := findSSHKey()
+			if err != nil {
+				return fmt.Errorf("SSH key problem: %w", err)
+			}
+			appState.SSHKeyPath = privateSSHKeyPath
+		}
+	}
+
+	return nil
+}
+
+// waitForSSH waits until the SSH daemon of the application accepts connections
+func waitForSSH(sshClient *ssh.Client) error {
+	cYellow.Println(".. waiting for SSH daemon to get ready")
+	testCounter := 0
+	for {
+		_, err := sshClient.Run("echo 1")
+		if err == nil {
+			cGreen.Println("     ready")
+			return nil
+		}
+
+		if testCounter > 12 {
+			// This prints the last error that occurs. It turned out the problem can actually be
+			// something else because this is the first time we try to connect to the SSH server.
+			cRed.Println(err.Error())
+			return errors.New("SSH daemon has not started in time")
+		}
+
+		testCounter++
+
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// sshClientForApp returns SSH client for the given application. If the key
+// is protected by a password it asks the user for it.
+func sshClientForApp(app *rostiapi.App, appState *state.RostiState) (*ssh.Client, error) {
+	if len(app.SSHAccess) == 0 {
+		return nil, errors.New("no SSH access found")
+	}
+
+	sshClient := &ssh.Client{
+		Server:     app.SSHAccess[0].Hostname,
+		Port:       int(app.SSHAccess[0].Port),
+		Username:   app.SSHAccess[0].Username,
+		SSHKeyPath: appState.SSHKeyPath,
+	}
+
+	if sshClient.IsKeyPasswordProtected() {
+		counter := 0
+		for {
+			var err error
+
+			fmt.Print("SSH key password: ")
+			sshClient.Passphrase, err = terminal.ReadPassword(0)
+			fmt.Println()
+			if err != nil {
+				return nil, fmt.Errorf("ssh key password input error: %v", err)
+			}
+
+			ok, err := sshClient.IsPasswordOk()
+			if err != nil {
+				return nil, fmt.Errorf("ssh key password check error: %v", err)
+			}
+
+			if ok {
+				break
+			}
+
+			counter += 1
+
+			if counter >= 3 {
+				return nil, fmt.Errorf("too many attempts for the SSH key password")
+			}
+		}
+	}
+
+	return sshClient, nil
+}
+
+// connectApp loads the state file and returns API client and SSH client of
+// the application. It's used by commands working with already deployed application.
+func connectApp() (*state.RostiState, *rostiapi.Client, *ssh.Client, error) {
+	config := config.Load()
+
+	cYellow.Println(".. loading state file")
+	appState, err := state.Load()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if appState.ApplicationID == 0 {
+		return nil, nil, nil, errors.New("application not found in the state file, deploy it first or import it")
+	}
+
+	err = ensureSSHKey(appState)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	client := &rostiapi.Client{
+		Token:      config.Token,
+		CompanyID:  appState.CompanyID,
+		ExtraError: os.Stderr,
+	}
+
+	cYellow.Println(".. loading application configuration")
+	app, err := client.GetApp(appState.ApplicationID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	sshClient, err := sshClientForApp(&app, appState)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return appState, client, sshClient, nil
+}
+
+// gitInfo returns current commit of git repository in the given directory
+// and whether the working tree contains uncommitted changes. Empty commit
+// is returned when git is not available or the directory is not a repository.
+func gitInfo(directory string) (string, bool) {
+	out, err := exec.Command("git", "-C", directory, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", false
+	}
+	commit := strings.TrimSpace(string(out))
+
+	out, err = exec.Command("git", "-C", directory, "status", "--porcelain").Output()
+	if err != nil {
+		return commit, false
+	}
+
+	return commit, len(strings.TrimSpace(string(out))) > 0
+}
+
+// secretsKeyPath returns path of the file with the key used to encrypt secrets
+func secretsKeyPath() string {
+	return path.Join(config.Directory(), "secrets.key")
+}
+
+// loadSecretsKey returns the key used to encrypt secrets. When generate is
+// true and there is no key yet a new one is created.
+func loadSecretsKey(generate bool) (*secrets.Key, error) {
+	key, err := secrets.LoadKey(secretsKeyPath())
+	if err == secrets.ErrNoKey && generate {
+		key, err = secrets.GenerateKey()
+		if err != nil {
+			return nil, err
+		}
+
+		err = secrets.SaveKey(secretsKeyPath(), key)
+		if err != nil {
+			return nil, err
+		}
+
+		cYellow.Printf(".. a new secrets key has been generated into %s, share it with your team or set it via %s\n", secretsKeyPath(), secrets.KeyEnvVariable)
+	} else if err == secrets.ErrNoKey {
+		return nil, fmt.Errorf("secrets key not found, set %s environment variable or put the key into %s", secrets.KeyEnvVariable, secretsKeyPath())
+	} else if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// decryptSecrets decrypts secrets defined in Rostifile and adds them into
+// the global environment variables. The encrypted secrets are removed from
+// the structure so the structure must not be written back into Rostifile.
+func decryptSecrets(rostifile *parser.Rostifile) error {
+	if len(rostifile.Secrets) == 0 {
+		return nil
+	}
+
+	key, err := loadSecretsKey(false)
+	if err != nil {
+		return err
+	}
+
+	if rostifile.Env == nil {
+		rostifile.Env = make(map[string]string, len(rostifile.Secrets))
+	}
+
+	for name, value := range rostifile.Secrets {
+		plaintext, err := secrets.Decrypt(key, value)
+		if err != nil {
+			return fmt.Errorf("secret %s: %w", name, err)
+		}
+		rostifile.Env[name] = plaintext
+	}
+	rostifile.Secrets = nil
+
+	return nil
+}
+
+// saveDeployRecord finishes the deploy record and saves it into the local
+// history file and on the server when SSH connection is available.
+func saveDeployRecord(record *history.Record, sshClient *ssh.Client, deployErr error) {
+	record.Finish(deployErr)
+
+	err := history.AppendLocal(record)
+	if err != nil {
+		cRed.Println("Warning:", err)
+	}
+
+	if sshClient == nil {
+		return
+	}
+
+	line, err := record.Encode()
+	if err != nil {
+		cRed.Println("Warning:", err)
+		return
+	}
+
+	err = sshClient.AppendFile(history.RemotePath, line)
+	if err != nil {
+		cRed.Println("Warning: deploy record couldn't be saved on the server:", err)
+	}
+}
+
+// acquireDeployLock takes the deploy lock on the server and returns a function
+// releasing it. The lock is released also when the deploy is interrupted.
+func acquireDeployLock(sshClient *ssh.Client) (func(), error) {
+	cYellow.Println(".. acquiring deploy lock")
+	deployLock, err := lock.Acquire(sshClient, lock.New())
+	if errors.Is(err, lock.ErrLocked) {
+		return nil, fmt.Errorf("%w, the application is locked by %s, use \"rostictl unlock --force\" if the lock is stale", err, deployLock.String())
+	} else if err != nil {
+		return nil, err
+	}
+
+	signals := make(chan os.Signal, 1)
+	done := make(chan bool)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+			cRed.Println("\n.. interrupted, releasing deploy lock")
+			err := lock.Release(sshClient, *deployLock)
+			if err != nil {
+				cRed.Println("Warning:", err)
+			}
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+
+		err := lock.Release(sshClient, *deployLock)
+		if err != nil {
+			cRed.Println("Warning:", err)
+		}
+	}, nil
+}
+
+// validateTechnology checks technology and its version against the ones
+// available in the application's runtime
+func validateTechnology(rostifile *parser.Rostifile, techs []rostiapi.AppTech, runtime string) error {
+	if rostifile.Technology == "" || rostifile.Technology == parser.TechnologyBinary || len(techs) == 0 {
+		return nil
+	}
+
+	var names, versions []string
+	for _, tech := range techs {
+		if !stringInSlice(tech.Name, names) {
+			names = append(names, tech.Name)
+		}
+		if tech.Name == rostifile.Technology && tech.Version != "" {
+			versions = append(versions, tech.Version)
+		}
+	}
+
+	if !stringInSlice(rostifile.Technology, names) {
+		return fmt.Errorf(
+			"technology %s is not available in runtime %s, valid options are: %s%s",
+			rostifile.Technology,
+			runtime,
+			strings.Join(names, ", "),
+			suggest.DidYouMean(rostifile.Technology, names),
+		)
+	}
+
+	if rostifile.TechnologyVersion != "" && len(versions) > 0 && !stringInSlice(rostifile.TechnologyVersion, versions) {
+		return fmt.Errorf(
+			"version %s of %s is not available in runtime %s, valid options are: %s%s",
+			rostifile.TechnologyVersion,
+			rostifile.Technology,
+			runtime,
+			strings.Join(versions, ", "),
+			suggest.DidYouMean(rostifile.TechnologyVersion, versions),
+		)
+	}
+
+	return nil
+}
+
+// technologyChanged returns true when the primary technology of the
+// application differs from the one in Rostifile
+func technologyChanged(status rostiapi.AppStatus, rostifile *parser.Rostifile) bool {
+	return status.PrimaryTech.Name != rostifile.Technology || (status.PrimaryTech.Version != rostifile.TechnologyVersion && rostifile.TechnologyVersion != "")
+}
+
+func stringInSlice(value string, values []string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// saveAssignedDomains writes *.rostiapp.cz domains assigned by the API into
+// Rostifile. The deploy continues even if Rostifile can't be written.
+func saveAssignedDomains(domains []string) {
+	var assigned []string
+	for _, domain := range domains {
+		if strings.HasSuffix(domain, ".rostiapp.cz") {
+			assigned = append(assigned, domain)
+		}
+	}
+	if len(assigned) == 0 {
+		return
+	}
+
+	rostifile, err := parser.ParseRaw()
+	if err == nil && len(rostifile.Domains) == 0 {
+		cYellow.Println(".. saving assigned domains into Rostifile:", strings.Join(assigned, ", "))
+		rostifile.Domains = assigned
+		err = parser.Write(*rostifile)
+	}
+	if err != nil {
+		cRed.Println("Warning: assigned domains couldn't be saved into Rostifile:", err)
+	}
+}
+
 func readLocalSSHPubKey(publicKeyPath string) (string, error) {
 	body, err := ioutil.ReadFile(publicKeyPath)
 	if err != nil {
@@ -309,11 +737,12 @@ func selectApp(client *rostiapi.Client) (uint, error) {
 	return appID, nil
 }
 
-// Selects plan based on Rostifile or default settings
+// Selects plan based on Rostifile. Zero is returned when Rostifile doesn't
+// set the plan, the backend's default is used for new applications and
+// existing ones keep their current plan.
 func selectPlan(client *rostiapi.Client, rostifile *parser.Rostifile) (uint, error) {
-	// TODO: implement something like default plan loaded from the API (needs support in the API)
 	if rostifile.Plan == "" {
-		rostifile.Plan = "start+"
+		return 0, nil
 	}
 
 	cYellow.Println(".. loading list of available plans")
@@ -322,14 +751,368 @@ func selectPlan(client *rostiapi.Client, rostifile *parser.Rostifile) (uint, err
 		return 0, err
 	}
 
-	var planID uint
+	return findPlanID(plans, rostifile.Plan)
+}
+
+// findPlanID returns ID of the plan with given name
+func findPlanID(plans []rostiapi.Plan, name string) (uint, error) {
+	var slugs []string
 	for _, plan := range plans {
-		if strings.ToLower(plan.Name) == strings.ToLower(rostifile.Plan) {
-			planID = plan.ID
+		if strings.ToLower(plan.Name) == strings.ToLower(name) {
+			return plan.ID, nil
 		}
+		slugs = append(slugs, strings.ToLower(plan.Name))
 	}
 
-	return planID, nil
+	return 0, fmt.Errorf(
+		"plan %s doesn't exist, valid plans are: %s%s",
+		name,
+		strings.Join(slugs, ", "),
+		suggest.DidYouMean(strings.ToLower(name), slugs),
+	)
+}
+
+// appMode returns mode of the application based on HTTPS setting in Rostifile
+func appMode(rostifile *parser.Rostifile) string {
+	if rostifile.HTTPS {
+		return "https+le"
+	}
+	return "http"
+}
+
+// sortedKeys returns keys of the map in alphabetical order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// renderEnvFile returns content of .env file with the global environment variables
+func renderEnvFile(rostifile *parser.Rostifile) string {
+	content := "# This file is gonna be rewritten by rostictl\n"
+	for _, key := range sortedKeys(rostifile.Env) {
+		content += key + "=" + shell.Quote(rostifile.Env[key]) + "\n"
+	}
+
+	return content
+}
+
+// writeEnvFile uploads environment variables defined in Rostifile into the container.
+// The file is removed when there are no variables so no stale secrets stay behind.
+func writeEnvFile(sshClient *ssh.Client, rostifile *parser.Rostifile) error {
+	if len(rostifile.Env) == 0 {
+		_, err := sshClient.Run("rm -f " + supervisor.EnvFilePath)
+		if err != nil {
+			return fmt.Errorf("removing %s error: %w", supervisor.EnvFilePath, err)
+		}
+		return nil
+	}
+
+	err := sshClient.WriteFile(supervisor.EnvFilePath, []byte(renderEnvFile(rostifile)), 0600)
+	if err != nil {
+		return fmt.Errorf("writing %s error: %w", supervisor.EnvFilePath, err)
+	}
+
+	return nil
+}
+
+// hookScript returns shell command running the deploy command with
+// supervisor's PATH, environment variables from .env file and the command's options.
+func hookScript(cmd parser.Command) string {
+	script := "set -e\n"
+	script += supervisor.ShellEnv + "\n"
+	for _, key := range sortedKeys(cmd.Env) {
+		script += "export " + key + "=" + shell.Quote(cmd.Env[key]) + "\n"
+	}
+	if cmd.Workdir != "" {
+		script += "cd " + shell.Quote(cmd.Workdir) + "\n"
+	}
+	script += cmd.Script
+
+	command := "/bin/sh -c " + shell.Quote(script)
+	if cmd.Timeout > 0 {
+		command = "timeout " + strconv.Itoa(cmd.Timeout) + " " + command
+	}
+
+	return command
+}
+
+// runHook runs a deploy command in the container and prints its output if it fails
+func runHook(sshClient *ssh.Client, cmd parser.Command) error {
+	cYellow.Print(".. running command: ")
+	cWhite.Println(cmd.String())
+
+	buf, err := sshClient.Run(hookScript(cmd))
+	if err != nil {
+		cRed.Println(buf.String())
+		if cmd.IgnoreErrors {
+			cYellow.Println("     error ignored:", err.Error())
+			return nil
+		}
+		return fmt.Errorf("command \"%s\" error: %w", cmd.String(), err)
+	}
+
+	return nil
+}
+
+// uploadFiles writes all files defined in Rostifile into the container
+func uploadFiles(sshClient *ssh.Client, rostifile *parser.Rostifile) error {
+	for _, filePath := range rostifile.FilePaths() {
+		file := rostifile.Files[filePath]
+
+		remotePath := path.Clean(filePath)
+		if !strings.HasPrefix(remotePath, "/srv/") {
+			return fmt.Errorf("file %s has to be located in /srv", filePath)
+		}
+
+		content, err := file.Load()
+		if err != nil {
+			return err
+		}
+
+		mode, err := file.FileMode()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("     %s\n", remotePath)
+		err = sshClient.WriteFile(remotePath, []byte(content), mode)
+		if err != nil {
+			return fmt.Errorf("uploading file %s error: %w", filePath, err)
+		}
+	}
+
+	return nil
+}
+
+// updateSupervisor uploads supervisor's configuration, removes programs that
+// are not defined anymore and restarts the changed ones. Returns what has changed.
+func updateSupervisor(sshClient *ssh.Client, rostifile *parser.Rostifile) (supervisor.Changes, error) {
+	content, err := supervisor.Render(rostifile)
+	if err != nil {
+		return supervisor.Changes{}, err
+	}
+
+	oldContent, err := sshClient.ReadFile(supervisor.ConfigPath)
+	if err != nil && !os.IsNotExist(err) {
+		return supervisor.Changes{}, fmt.Errorf("reading supervisor config error: %w", err)
+	}
+
+	err = sshClient.SendFile(supervisor.ConfigPath, content)
+	if err != nil {
+		return supervisor.Changes{}, fmt.Errorf("updating supervisor config error: %w", err)
+	}
+
+	_, err = sshClient.Run("supervisorctl reread")
+	if err != nil {
+		return supervisor.Changes{}, fmt.Errorf("refreshing supervisor error: %w", err)
+	}
+	_, err = sshClient.Run("supervisorctl update")
+	if err != nil {
+		return supervisor.Changes{}, fmt.Errorf("updating supervisor error: %w", err)
+	}
+
+	return supervisor.Diff(string(oldContent), content), nil
+}
+
+// printSupervisorChanges prints which programs were added, changed or removed
+func printSupervisorChanges(changes supervisor.Changes) {
+	if changes.Empty() {
+		cGrey.Println("     no changes")
+	}
+	for _, name := range changes.Added {
+		cGreen.Printf("     + %s (added)\n", name)
+	}
+	for _, name := range changes.Changed {
+		cYellow.Printf("     ~ %s (changed)\n", name)
+	}
+	for _, name := range changes.Removed {
+		cRed.Printf("     - %s (removed)\n", name)
+	}
+}
+
+// waitForProcesses waits until all processes defined in Rostifile are up. When
+// some of them fails or doesn't start in time, last lines of its log are printed.
+func waitForProcesses(sshClient *ssh.Client, rostifile *parser.Rostifile, timeout time.Duration, logLines int) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		// supervisorctl returns non-zero exit code when some of the processes is not running
+		buf, err := sshClient.Run("supervisorctl status")
+		if err != nil && (buf == nil || buf.Len() == 0) {
+			return fmt.Errorf("loading status of supervisor processes error: %w", err)
+		}
+		statuses := supervisor.ParseStatus(buf.String())
+
+		var pending []string
+		var failed []parser.Process
+		var fatal bool
+		for _, process := range rostifile.Processes {
+			instances := supervisor.StatusesOf(process, statuses)
+			if len(instances) == 0 {
+				pending = append(pending, process.Name+" (not found)")
+				failed = append(failed, process)
+				continue
+			}
+
+			for _, instance := range instances {
+				if !supervisor.IsUp(process, instance.State) {
+					pending = append(pending, instance.Name+" ("+instance.State+")")
+					failed = append(failed, process)
+					fatal = fatal || instance.State == supervisor.StateFatal
+					break
+				}
+			}
+		}
+
+		if len(pending) == 0 {
+			return nil
+		}
+
+		if fatal || time.Now().After(deadline) {
+			for _, process := range failed {
+				printProcessLog(sshClient, process, logLines)
+			}
+			return fmt.Errorf("processes are not running: %s", strings.Join(pending, ", "))
+		}
+
+		time.Sleep(2 * time.Second)
+	}
+}
+
+// printProcessLog prints last lines of the process's log
+func printProcessLog(sshClient *ssh.Client, process parser.Process, lines int) {
+	logFile := supervisor.LogFile(process)
+
+	cYellow.Printf(".. last %d lines of %s:\n", lines, logFile)
+	buf, err := sshClient.Run("tail -n " + strconv.Itoa(lines) + " " + shell.Quote(logFile))
+	if err != nil {
+		cRed.Println("     reading the log error:", err.Error())
+		return
+	}
+	fmt.Println(buf.String())
+}
+
+// Backup of the code used to restore it when the health check fails
+const rollbackArchivePath = "/srv/.rosti/rollback.tar"
+
+// backupCode archives current content of /srv/app so it can be restored later
+func backupCode(sshClient *ssh.Client) error {
+	cmd := "/bin/sh -c \"mkdir -p /srv/.rosti /srv/app && tar cf " + rollbackArchivePath + " -C /srv/app .\""
+	buf, err := sshClient.Run(cmd)
+	if err != nil {
+		return fmt.Errorf("code backup error: %w (%s)", err, strings.TrimSpace(buf.String()))
+	}
+
+	return nil
+}
+
+// restoreCode replaces content of /srv/app by the backup and restarts processes
+func restoreCode(sshClient *ssh.Client, rostifile *parser.Rostifile) error {
+	cmd := "/bin/sh -c \"find /srv/app -mindepth 1 -delete && tar xf " + rollbackArchivePath + " -C /srv/app\""
+	buf, err := sshClient.Run(cmd)
+	if err != nil {
+		return fmt.Errorf("code restore error: %w (%s)", err, strings.TrimSpace(buf.String()))
+	}
+
+	for _, process := range rostifile.Processes {
+		// Processes with numprocs are a group and all its instances have to be restarted
+		name := process.Name
+		if process.Numprocs > 1 {
+			name += ":*"
+		}
+		buf, err = sshClient.Run("supervisorctl restart " + shell.Quote(name))
+		if err != nil {
+			return fmt.Errorf("restarting process %s error: %w (%s)", process.Name, err, strings.TrimSpace(buf.String()))
+		}
+	}
+
+	return nil
+}
+
+// healthcheckURLs returns URLs checked by the health check
+func healthcheckURLs(rostifile *parser.Rostifile, domains []string) []string {
+	scheme := "http"
+	if rostifile.HTTPS {
+		scheme = "https"
+	}
+
+	urls := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		urls = append(urls, scheme+"://"+domain+rostifile.Healthcheck.Path)
+	}
+
+	return urls
+}
+
+// Crontab file installed by older versions of rostictl
+const crontabPath = "/srv/conf/crontab"
+
+// crontabJobs returns crontab lines of all jobs defined in Rostifile
+func crontabJobs(rostifile *parser.Rostifile) []string {
+	var jobs []string
+	// Variables like MAILTO have to stay in front of the jobs
+	for _, job := range rostifile.Crontabs {
+		if job.IsVariable() {
+			jobs = append(jobs, job.Raw)
+		}
+	}
+	for _, job := range rostifile.CronJobs() {
+		jobs = append(jobs, crontab.Line(job))
+	}
+
+	return jobs
+}
+
+// readCrontab returns current crontab of the user in the container
+func readCrontab(sshClient *ssh.Client) (string, error) {
+	buf, err := sshClient.Run("crontab -l")
+	if err != nil {
+		// crontab returns an error when the user has no crontab yet
+		if buf != nil && strings.Contains(buf.String(), "no crontab") {
+			return "", nil
+		}
+		return "", fmt.Errorf("reading crontab error: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// updateCrontab replaces block of rostictl's jobs in the crontab and keeps the rest of it
+func updateCrontab(sshClient *ssh.Client, rostifile *parser.Rostifile) error {
+	current, err := readCrontab(sshClient)
+	if err != nil {
+		return err
+	}
+
+	// Older versions of rostictl replaced the whole crontab by content of this file
+	legacy, err := sshClient.ReadFile(crontabPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("reading %s error: %w", crontabPath, err)
+	}
+
+	merged := crontab.Merge(crontab.Adopt(current, string(legacy)), crontabJobs(rostifile))
+	if merged != current {
+		// The file is not written anymore, it would be adopted again by the next deploy
+		_, err = sshClient.Run("printf %s " + shell.Quote(merged) + " | crontab -")
+		if err != nil {
+			return fmt.Errorf("refreshing crontab error: %w", err)
+		}
+	}
+
+	// Legacy crontab is adopted only once
+	if legacy != nil {
+		_, err = sshClient.Run("rm -f " + crontabPath)
+		if err != nil {
+			return fmt.Errorf("removing %s error: %w", crontabPath, err)
+		}
+	}
+
+	return nil
 }
 
 // Selects runtime image based on rostifile
@@ -442,7 +1225,7 @@ func printAppStatus(domains []string, status rostiapi.AppStatus, app rostiapi.Ap
 
 		for _, tech := range status.Techs {
 			if tech.Name == status.PrimaryTech.Name && tech.Version == status.PrimaryTech.Version {
-				fmt.Printf(cGreen.Sprint("  %-10s %s <--\n"), tech.Name, tech.Version)
+				cGreen.Printf("  %-10s %s <--\n", tech.Name, tech.Version)
 			} else {
 				fmt.Printf("  %-10s %s\n", tech.Name, tech.Version)
 			}
@@ -452,6 +1235,38 @@ func printAppStatus(domains []string, status rostiapi.AppStatus, app rostiapi.Ap
 	fmt.Println("")
 }
 
+// printReport prints errors and warnings found in Rostifile
+func printReport(report parser.Report) {
+	if len(report.Errors) > 0 {
+		cRed.Println("Errors:")
+		for _, err := range report.Errors {
+			fmt.Println("  " + err.Error())
+		}
+	}
+
+	if len(report.Warnings) > 0 {
+		cYellow.Println("Warnings:")
+		for _, warning := range report.Warnings {
+			fmt.Println("  " + warning)
+		}
+	}
+}
+
+// before processes global options before any command runs
+func before(c *cli.Context) error {
+	err := noColor(c)
+	if err != nil {
+		return err
+	}
+
+	err = selectRostifile(c)
+	if err != nil {
+		return err
+	}
+
+	return selectEnvironment(c)
+}
+
 // noColor disables color output
 func noColor(c *cli.Context) error {
 	if c.Bool("no-color") {
@@ -460,3 +1275,117 @@ func noColor(c *cli.Context) error {
 
 	return nil
 }
+
+// selectRostifile changes working directory to the one with Rostifile so
+// source_path, state file and history are relative to Rostifile. Rostifile
+// is given by --file option or it's searched in the current directory and
+// its parents.
+func selectRostifile(c *cli.Context) error {
+	if c.String("chdir") != "" {
+		err := os.Chdir(c.String("chdir"))
+		if err != nil {
+			return fmt.Errorf("changing directory error: %w", err)
+		}
+	}
+
+	if c.String("file") != "" {
+		dir, name := filepath.Split(c.String("file"))
+		if dir != "" {
+			err := os.Chdir(dir)
+			if err != nil {
+				return fmt.Errorf("changing directory to Rostifile's location error: %w", err)
+			}
+		}
+		parser.SetFilePath("./" + name)
+
+		return nil
+	}
+
+	// New Rostifile is always created in the current directory
+	if c.Args().First() == "init" {
+		return nil
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	dir := findRostifileDir(cwd)
+	if dir != "" && dir != cwd {
+		err = os.Chdir(dir)
+		if err != nil {
+			return fmt.Errorf("changing directory to Rostifile's location error: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// findRostifileDir returns the nearest directory with Rostifile, starting in
+// dir and going up to its parents, the way git looks for .git. The search
+// stops at a workspace root. Empty string is returned when there is no
+// Rostifile.
+func findRostifileDir(dir string) string {
+	for {
+		_, err := os.Stat(filepath.Join(dir, parser.FileName))
+		if err == nil {
+			return dir
+		}
+
+		_, err = os.Stat(filepath.Join(dir, filepath.Base(workspace.FilePath)))
+		if err == nil {
+			return ""
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// selectEnvironment switches Rostifile, state file and local history to the
+// environment selected by --env option
+func selectEnvironment(c *cli.Context) error {
+	name := c.String("env")
+
+	err := parser.SetEnvironment(name)
+	if err != nil {
+		return err
+	}
+	state.SetEnvironment(name)
+	history.SetEnvironment(name)
+	parser.SetLookup(lookupVariable)
+
+	return nil
+}
+
+// lookupVariable returns value of a variable used in Rostifile. Environment
+// variables take precedence over the built-in ones.
+func lookupVariable(name string) (string, bool) {
+	value, ok := os.LookupEnv(name)
+	if ok {
+		return value, true
+	}
+
+	switch name {
+	case "ROSTI_ENV":
+		return parser.Environment(), true
+	case "GIT_SHA":
+		commit, _ := gitInfo(".")
+		return commit, commit != ""
+	case "ROSTI_APP_ID":
+		// The state file is not created just to read the ID
+		if !state.Exists() {
+			return "", false
+		}
+		appState, err := state.Load()
+		if err != nil || appState.ApplicationID == 0 {
+			return "", false
+		}
+		return strconv.Itoa(int(appState.ApplicationID)), true
+	}
+
+	return "", false
+}
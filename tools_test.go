@@ -0,0 +1,119 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rosti-cz/cli/src/parser"
+	"github.com/rosti-cz/cli/src/rostiapi"
+	"github.com/rosti-cz/cli/src/shell"
+	"github.com/rosti-cz/cli/src/supervisor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindRostifileDir(t *testing.T) {
+	root, err := ioutil.TempDir("", "rostictl")
+	assert.Nil(t, err)
+	defer os.RemoveAll(root)
+
+	nested := filepath.Join(root, "project", "src", "app")
+	assert.Nil(t, os.MkdirAll(nested, 0755))
+	assert.Equal(t, "", findRostifileDir(nested))
+
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(root, "project", "Rostifile"), []byte("name: test\n"), 0644))
+	assert.Equal(t, filepath.Join(root, "project"), findRostifileDir(nested))
+
+	// Workspace root stops the search
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(root, "project", "src", "Rostiworkspace"), []byte("apps: {}\n"), 0644))
+	assert.Equal(t, "", findRostifileDir(nested))
+}
+
+func TestValidateTechnology(t *testing.T) {
+	techs := []rostiapi.AppTech{
+		{Name: "python", Version: "3.9.12"},
+		{Name: "python", Version: "3.10.4"},
+		{Name: "node", Version: "16.15.0"},
+	}
+
+	assert.Nil(t, validateTechnology(&parser.Rostifile{Technology: "python", TechnologyVersion: "3.10.4"}, techs, "rosti/runtime:2022.04-1"))
+	assert.Nil(t, validateTechnology(&parser.Rostifile{Technology: "binary"}, techs, "rosti/runtime:2022.04-1"))
+
+	err := validateTechnology(&parser.Rostifile{Technology: "pyhton"}, techs, "rosti/runtime:2022.04-1")
+	assert.Equal(t, "technology pyhton is not available in runtime rosti/runtime:2022.04-1, valid options are: python, node (did you mean python?)", err.Error())
+
+	err = validateTechnology(&parser.Rostifile{Technology: "python", TechnologyVersion: "3.10.5"}, techs, "rosti/runtime:2022.04-1")
+	assert.Equal(t, "version 3.10.5 of python is not available in runtime rosti/runtime:2022.04-1, valid options are: 3.9.12, 3.10.4 (did you mean 3.10.4?)", err.Error())
+}
+
+func TestFindPlanID(t *testing.T) {
+	plans := []rostiapi.Plan{{ID: 1, Name: "Start"}, {ID: 2, Name: "Start+"}, {ID: 3, Name: "Business"}}
+
+	planID, err := findPlanID(plans, "start+")
+	assert.Nil(t, err)
+	assert.Equal(t, uint(2), planID)
+
+	_, err = findPlanID(plans, "bussines")
+	assert.Equal(t, "plan bussines doesn't exist, valid plans are: start, start+, business (did you mean business?)", err.Error())
+}
+
+func TestHookScript(t *testing.T) {
+	prefix := "set -e\n" +
+		"export PATH='" + supervisor.PATH + "'; set -a; [ -f /srv/app/.env ] && . /srv/app/.env; set +a\n"
+
+	assert.Equal(t,
+		"/bin/sh -c "+shell.Quote(prefix+"make migrate"),
+		hookScript(parser.Command{Script: "make migrate"}),
+	)
+
+	// ignore_errors is handled by runHook, the script stays the same
+	assert.Equal(t,
+		"/bin/sh -c "+shell.Quote(prefix+"make migrate"),
+		hookScript(parser.Command{Script: "make migrate", IgnoreErrors: true}),
+	)
+
+	// set -e comes before cd so a missing workdir stops the script
+	assert.Equal(t,
+		"timeout 60 /bin/sh -c "+shell.Quote(prefix+
+			"export A='1'\n"+
+			"export B='it'\\''s'\n"+
+			"cd '/srv/app/backend'\n"+
+			"./manage.py migrate"),
+		hookScript(parser.Command{
+			Script:  "./manage.py migrate",
+			Workdir: "/srv/app/backend",
+			Env:     map[string]string{"B": "it's", "A": "1"},
+			Timeout: 60,
+		}),
+	)
+}
+
+func TestRunBuildCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rostictl")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// Commands run in source_path and the output directory is checked after them
+	rostifile := &parser.Rostifile{
+		SourcePath:    dir,
+		BuildCommands: []string{"mkdir dist", "touch dist/index.html"},
+		BuildOutput:   "dist",
+	}
+	assert.Nil(t, runBuildCommands(rostifile))
+	assert.FileExists(t, filepath.Join(dir, "dist", "index.html"))
+
+	// Failing command stops the build before the next one runs
+	rostifile.BuildCommands = []string{"exit 3", "touch built"}
+	err = runBuildCommands(rostifile)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "build command \"exit 3\" failed")
+	assert.NoFileExists(t, filepath.Join(dir, "built"))
+
+	// Missing output directory
+	rostifile.BuildCommands = []string{"true"}
+	rostifile.BuildOutput = "build"
+	err = runBuildCommands(rostifile)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "directory to upload not found")
+}
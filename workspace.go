@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rosti-cz/cli/src/config"
+	"github.com/rosti-cz/cli/src/rostiapi"
+	"github.com/rosti-cz/cli/src/ssh"
+	"github.com/rosti-cz/cli/src/state"
+	"github.com/rosti-cz/cli/src/workspace"
+	"github.com/urfave/cli/v2"
+)
+
+// workspaceResult is outcome of deploy of a single app of the workspace
+type workspaceResult struct {
+	Name     string
+	Output   bytes.Buffer
+	Err      error
+	Drift    bool
+	Duration time.Duration
+}
+
+// workspaceUpArgs returns arguments of up command run for each app of the workspace
+func workspaceUpArgs(c *cli.Context) []string {
+	args := []string{}
+	if c.Bool("no-color") {
+		args = append(args, "--no-color")
+	}
+	if c.String("env") != "" {
+		args = append(args, "--env", c.String("env"))
+	}
+
+	// Progress of the upload is useless in captured output
+	args = append(args, "up", "--quiet")
+	if c.Int("company") != 0 {
+		args = append(args, "--company", strconv.Itoa(c.Int("company")))
+	}
+	if c.Bool("force-init") {
+		args = append(args, "--force-init")
+	}
+	if c.Bool("dry-run") {
+		args = append(args, "--dry-run")
+	}
+	if c.String("limit-rate") != "" {
+		args = append(args, "--limit-rate", c.String("limit-rate"))
+	}
+	args = append(args, "--process-timeout", strconv.Itoa(c.Int("process-timeout")))
+	args = append(args, "--log-lines", strconv.Itoa(c.Int("log-lines")))
+
+	return args
+}
+
+// workspaceEnv returns environment of the apps' processes, Rostifile given
+// to the workspace itself would override Rostifile of every app
+func workspaceEnv() []string {
+	env := []string{}
+	for _, variable := range os.Environ() {
+		if !strings.HasPrefix(variable, "ROSTIFILE=") {
+			env = append(env, variable)
+		}
+	}
+
+	return env
+}
+
+// printPrefixed prints every line of the output with the app's name in front of it
+func printPrefixed(name string, output []byte) {
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		cGrey.Print("[" + name + "] ")
+		fmt.Println(scanner.Text())
+	}
+}
+
+// checkWorkspaceInput makes sure no app asks for user's input during the
+// deploy. The apps run in background without a terminal, so company and
+// a SSH key without passphrase have to be known in advance.
+func checkWorkspaceInput(c *cli.Context, token string, ws *workspace.Workspace, names []string) error {
+	var problems []string
+	var withoutCompany []string
+
+	for _, name := range names {
+		dir := filepath.Clean(ws.Apps[name].Path)
+		appState, err := state.LoadFrom(dir)
+		if err != nil {
+			return fmt.Errorf("app %s: %w", name, err)
+		}
+
+		if appState.CompanyID == 0 && c.Int("company") == 0 {
+			withoutCompany = append(withoutCompany, name)
+		}
+
+		keyPath := appState.SSHKeyPath
+		if keyPath != "" && !filepath.IsAbs(keyPath) {
+			keyPath = filepath.Join(dir, keyPath)
+		}
+		if keyPath == "" {
+			problems = append(problems, name+": no SSH key is set in the state file")
+		} else if _, err := os.Stat(keyPath); err != nil {
+			problems = append(problems, name+": SSH key "+keyPath+" doesn't exist")
+		} else if (&ssh.Client{SSHKeyPath: keyPath}).IsKeyPasswordProtected() {
+			problems = append(problems, name+": SSH key "+keyPath+" is protected by a passphrase")
+		}
+	}
+
+	// Company is picked automatically only when there is just one
+	if len(withoutCompany) > 0 {
+		client := rostiapi.Client{
+			Token:      token,
+			ExtraError: os.Stderr,
+		}
+		companies, err := client.GetCompanies()
+		if err != nil {
+			return err
+		}
+		if len(companies) != 1 {
+			for _, name := range withoutCompany {
+				problems = append(problems, name+": company is not set in the state file")
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf(
+			"these apps would ask for input which is not possible when the workspace is deployed, run \"rostictl up\" in their directories first or use --company:\n  %s",
+			strings.Join(problems, "\n  "),
+		)
+	}
+
+	return nil
+}
+
+// Deploys selected apps of the workspace in parallel
+func commandUpWorkspace(c *cli.Context) error {
+	if c.Bool("all") && c.Args().Len() > 0 {
+		return errors.New("use either --all or names of the apps, not both")
+	}
+
+	cYellow.Println(".. loading workspace file")
+	ws, err := workspace.Load()
+	if err != nil {
+		return err
+	}
+
+	names, err := ws.Select(c.Args().Slice())
+	if err != nil {
+		return err
+	}
+
+	// Asks for the API token here when needed, the apps can't do it themselves
+	cfg := config.Load()
+
+	err = checkWorkspaceInput(c, cfg.Token, ws, names)
+	if err != nil {
+		return err
+	}
+
+	// The same binary deploys every app in its own directory so apps don't share any state
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locating rostictl binary error: %w", err)
+	}
+	args := workspaceUpArgs(c)
+
+	jobs := c.Int("jobs")
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	results := make([]*workspaceResult, len(names))
+	slots := make(chan bool, jobs)
+	var wg sync.WaitGroup
+	var outputLock sync.Mutex
+
+	for i, name := range names {
+		results[i] = &workspaceResult{Name: name}
+
+		wg.Add(1)
+		go func(result *workspaceResult) {
+			defer wg.Done()
+			slots <- true
+			defer func() { <-slots }()
+
+			outputLock.Lock()
+			cYellow.Printf(".. deploying %s\n", result.Name)
+			outputLock.Unlock()
+
+			started := time.Now()
+			cmd := exec.Command(executable, args...)
+			cmd.Dir = filepath.Clean(ws.Apps[result.Name].Path)
+			cmd.Env = workspaceEnv()
+			cmd.Stdout = &result.Output
+			cmd.Stderr = &result.Output
+			result.Err = cmd.Run()
+			result.Duration = time.Since(started)
+
+			var exitErr *exec.ExitError
+			if c.Bool("dry-run") && errors.As(result.Err, &exitErr) && exitErr.ExitCode() == driftExitCode {
+				result.Err = nil
+				result.Drift = true
+			}
+
+			// Output of every app is printed at once so it's not mixed with the others
+			outputLock.Lock()
+			fmt.Println("")
+			printPrefixed(result.Name, result.Output.Bytes())
+			outputLock.Unlock()
+		}(results[i])
+	}
+	wg.Wait()
+
+	var failed int
+	cGrey.Printf("\n  %-20s  %9s  %s\n", "App", "Duration", "Result")
+	cGrey.Printf("  %-20s  %9s  %s\n", "--------------------", "---------", "-------")
+	for _, result := range results {
+		fmt.Printf("  %-20s  %9s  ", result.Name, result.Duration.Round(time.Second))
+		switch {
+		case result.Err != nil:
+			failed++
+			cRed.Println("failed")
+		case result.Drift:
+			cYellow.Println("changes")
+		default:
+			cGreen.Println("ok")
+		}
+	}
+	fmt.Println("")
+
+	if failed > 0 {
+		return cli.Exit(fmt.Sprintf("%d of %d apps failed", failed, len(results)), 1)
+	}
+
+	return nil
+}